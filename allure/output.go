@@ -0,0 +1,41 @@
+package allure
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ParseOutputDestination interprets the -o flag's value as either a plain
+// local directory (the original behavior) or a destination URL - s3://,
+// gs:// or zip:// - selecting a Sink the same way --artifact-sink does.
+// When it returns remote true, localDir is empty and the caller must
+// download to a directory of its own choosing (e.g. a temp dir), since
+// GetArtifacts' resumable downloads still need local staging: the server
+// doesn't support resuming a partial upload to S3/GCS/a zip entry the way
+// it does a Range request, so true zero-staging streaming isn't possible
+// without giving up resume support. file:// is accepted as an explicit
+// spelling of a local directory.
+func ParseOutputDestination(o string) (localDir string, sinkCfg SinkConfig, remote bool, err error) {
+	if o == "" || !strings.Contains(o, "://") {
+		return o, SinkConfig{}, false, nil
+	}
+
+	u, err := url.Parse(o)
+	if err != nil {
+		return "", SinkConfig{}, false, fmt.Errorf("invalid -o destination %q: %w", o, err)
+	}
+
+	switch u.Scheme {
+	case "file":
+		return u.Path, SinkConfig{}, false, nil
+	case "s3":
+		return "", SinkConfig{Provider: "s3", Bucket: u.Host, Prefix: strings.Trim(u.Path, "/")}, true, nil
+	case "gs":
+		return "", SinkConfig{Provider: "gcs", Bucket: u.Host, Prefix: strings.Trim(u.Path, "/")}, true, nil
+	case "zip":
+		return "", SinkConfig{Provider: "zip", Bucket: u.Host + u.Path}, true, nil
+	default:
+		return "", SinkConfig{}, false, fmt.Errorf("unsupported -o scheme %q, must be one of [s3, gs, zip, file]", u.Scheme)
+	}
+}
@@ -1,17 +1,21 @@
 package allure
 
 import (
+	"cli/logging"
 	"cli/request"
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/otiai10/copy"
 	"io"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"path"
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -21,47 +25,115 @@ type ArtifactTree struct {
 	Name   string `json:"name"`
 }
 
-type FileNode struct {
-	ID       string `json:"id"`
-	IsFile   bool   `json:"is_file"`
-	Name     string `json:"name"`
-	Downloaded bool `json:"downloaded"`
+// fileDescriptor identifies one artifact file discovered by GetFolder,
+// ready for a worker to download.
+type fileDescriptor struct {
+	ID string
 }
 
-var maxConcurrentDownloads = 10 // Limit the number of concurrent downloads.
-
-func GetArtifacts(host string, token string, runId string, whereToSave string) {
-  fmt.Println("Start downloading artifacts")
-
-  var fileTree []FileNode
-  var wg sync.WaitGroup
-
-  // Semaphore and error channel
-  sem := make(chan struct{}, maxConcurrentDownloads)
-  errors := make(chan error)
+// DownloadConcurrency is how many files GetArtifacts downloads at once.
+// Overridden by --download-concurrency.
+var DownloadConcurrency = 10
+
+// maxConcurrentListings bounds how many folder-listing requests GetFolder
+// has in flight at once while it walks the artifact tree. It is
+// deliberately independent of DownloadConcurrency: listing a folder is
+// cheap compared to downloading a file, so it doesn't need to be
+// user-tunable.
+var maxConcurrentListings = 10
+
+// ShowProgress controls whether the aggregate download progress line is
+// printed while downloading artifacts. Disabled by --silent or
+// --no-progress, or automatically when stderr is not a TTY.
+var ShowProgress = true
+
+// downloadStats tracks the aggregate progress of an in-flight GetArtifacts
+// call: how many files GetFolder has discovered so far, how many of those
+// have finished downloading, and how many bytes have been written. All
+// fields are updated with the atomic package since they're read from the
+// progress-printing goroutine while being written from GetFolder and the
+// download workers.
+type downloadStats struct {
+	seen      int64
+	completed int64
+	bytes     int64
+}
 
-  // Error handling goroutine
-  go func() {
-      for err := range errors {
-          if err != nil {
-              fmt.Println("Error during download:", err)
-          }
-      }
-  }()
+// GetArtifacts downloads a run's Allure results into whereToSave. Files are
+// discovered by GetFolder and fed to a pool of DownloadConcurrency workers,
+// so large runs with thousands of attachments download in parallel instead
+// of one at a time; a resumed or interrupted download picks up from
+// whatever bytes already made it to disk rather than restarting from
+// scratch. When sink is non-nil, each file is mirrored to it as it
+// downloads - the response body is teed to the sink and the local file at
+// once, so a multi-GB run is never staged on disk before it starts
+// reaching the sink - and a manifest.json summarizing the mirror is
+// uploaded once downloading finishes; stats, if non-nil, seeds the
+// manifest's pass/fail/ignored counts. Canceling ctx stops tree traversal
+// and downloads as soon as their current HTTP request returns;
+// already-downloaded files are left on disk.
+func GetArtifacts(ctx context.Context, host string, token string, runId string, whereToSave string, sink Sink, stats *request.RunStats) error {
+	fmt.Println("Start downloading artifacts")
+
+	files := make(chan fileDescriptor, DownloadConcurrency)
+	progress := &downloadStats{}
+	go GetFolder(ctx, host, token, runId, files, progress)
+
+	stopProgress := make(chan struct{})
+	progressDone := make(chan struct{})
+	if ShowProgress {
+		go printProgressEverySecond(progress, stopProgress, progressDone)
+	} else {
+		close(progressDone)
+	}
 
-  // Step 1: Traverse and store file tree
-  traverseAndStoreFileTree(host, token, runId, &fileTree, &wg, sem)
-  wg.Wait()  // Wait for file tree traversal to complete
+	errs := make(chan error, DownloadConcurrency)
+	var downloadErr error
+	errsDone := make(chan struct{})
+	go func() {
+		defer close(errsDone)
+		for err := range errs {
+			logging.L().Error("artifact download failed", "error", err)
+			if downloadErr == nil {
+				downloadErr = err
+			}
+		}
+	}()
+
+	// streamed records the sha256 of every file mirrored to sink inline with
+	// its download, so the post-processing mirrorToSink pass below doesn't
+	// re-read (and re-upload) it from disk - only files it couldn't stream
+	// (resumed/already-present) or that updateJsonPaths rewrites afterward
+	// need that second pass.
+	var streamed sync.Map
+
+	var wg sync.WaitGroup
+	for i := 0; i < DownloadConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for fd := range files {
+				if ctx.Err() != nil {
+					continue
+				}
+				if err := downloadFileWithRetry(ctx, host, token, fd.ID, whereToSave, runId, sink, &streamed, 3, progress); err != nil {
+					errs <- err
+					continue
+				}
+				atomic.AddInt64(&progress.completed, 1)
+			}
+		}()
+	}
+	wg.Wait()
 
-  // Steps 2-3: Check for new files and redownload failed ones
-  for {
-    if !downloadFilesAndCheckForNew(host, token, runId, &fileTree, whereToSave, sem, errors, 3) {  // Assuming 3 retries
-      break
-    }
-    time.Sleep(time.Duration(60) * time.Second)
-  }
+	close(errs)
+	<-errsDone
+	close(stopProgress)
+	<-progressDone
 
-  close(errors)  // Close the error channel after all operations are done
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
 
 	// Post-processing: move contents from runId folder to root
 	relocateContents(whereToSave, runId)
@@ -69,117 +141,240 @@ func GetArtifacts(host string, token string, runId string, whereToSave string) {
 	updateJsonPaths(whereToSave)
 
 	fmt.Println("Finish downloading artifacts ")
+
+	if downloadErr != nil {
+		return fmt.Errorf("one or more artifacts failed to download: %w", downloadErr)
+	}
+
+	if sink != nil {
+		if err := mirrorToSink(ctx, sink, whereToSave, runId, stats, &streamed); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isRewrittenAllureJSON reports whether key is one of the Allure result
+// JSON files updateJsonPaths rewrites after all downloads finish - such a
+// file must always be (re-)mirrored from disk after post-processing even
+// if it was already streamed to sink during download, since its content on
+// disk changes afterward.
+func isRewrittenAllureJSON(key string) bool {
+	return strings.HasSuffix(key, ".json") && strings.Contains(key, "report/allure-results/")
+}
+
+// mirrorToSink walks the downloaded artifacts in whereToSave and copies
+// each one to sink that wasn't already streamed there during download (see
+// GetArtifacts), then uploads a manifest.json summarizing the mirror.
+func mirrorToSink(ctx context.Context, sink Sink, whereToSave string, runId string, stats *request.RunStats, streamed *sync.Map) error {
+	manifest := Manifest{
+		RunID:     runId,
+		Artifacts: make(map[string]string),
+	}
+	if stats != nil {
+		manifest.Passed = stats.Passed.Int64
+		manifest.Failed = stats.Failed.Int64
+		manifest.Ignored = stats.Ignored.Int64
+	}
+
+	err := filepath.Walk(whereToSave, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		key, err := filepath.Rel(whereToSave, filePath)
+		if err != nil {
+			return fmt.Errorf("failed to compute relative path for %s: %w", filePath, err)
+		}
+		key = filepath.ToSlash(key)
+
+		if sum, ok := streamed.Load(key); ok && !isRewrittenAllureJSON(key) {
+			manifest.Artifacts[key] = sum.(string)
+			return nil
+		}
+
+		f, err := os.Open(filePath)
+		if err != nil {
+			return fmt.Errorf("failed to open %s for mirroring: %w", filePath, err)
+		}
+		defer f.Close()
+
+		sum, err := sink.Put(ctx, key, f)
+		if err != nil {
+			return err
+		}
+		manifest.Artifacts[key] = sum
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to mirror artifacts to sink: %w", err)
+	}
+
+	return sink.Finalize(ctx, manifest)
 }
 
-func traverseAndStoreFileTree(host string, token string, folderID string, fileTree *[]FileNode, wg *sync.WaitGroup, sem chan struct{}) {
-  sem <- struct{}{} // Acquire semaphore
-	resp := request.SendGetRequest("https://"+host+"/api/v1/artifact/"+folderID, token)
-  <-sem // Release semaphore
+// GetFolder recursively enumerates the artifact tree rooted at folderID,
+// sending one fileDescriptor per file (not subfolder) to files as it's
+// discovered, then closes files once the whole tree has been visited (or
+// ctx is canceled). Folder listings run up to maxConcurrentListings at a
+// time; downloading is the caller's responsibility.
+func GetFolder(ctx context.Context, host string, token string, folderID string, files chan<- fileDescriptor, progress *downloadStats) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxConcurrentListings)
+	wg.Add(1)
+	go listFolder(ctx, host, token, folderID, files, progress, &wg, sem)
+	wg.Wait()
+	close(files)
+}
 
-	if resp == nil || resp.Body == nil {
+func listFolder(ctx context.Context, host string, token string, folderID string, files chan<- fileDescriptor, progress *downloadStats, wg *sync.WaitGroup, sem chan struct{}) {
+	defer wg.Done()
+	if ctx.Err() != nil {
 		return
 	}
-	defer resp.Body.Close()
 
-	bodyBytes, err := io.ReadAll(resp.Body)
+	sem <- struct{}{} // Acquire semaphore
+	resp, err := request.SendGetRequest(ctx, "https://"+host+"/api/v1/artifact/"+folderID, token)
+	<-sem // Release semaphore
+
 	if err != nil {
-		fmt.Println("Error reading response:", err.Error())
+		logging.L().Error("failed to list artifact folder", "folder", folderID, "error", err)
 		return
 	}
+	defer resp.Body.Close()
 
 	var folders []ArtifactTree
-	err = json.Unmarshal(bodyBytes, &folders)
-	if err != nil {
-		fmt.Println("Failed to unmarshal response:", err.Error())
+	if err := json.NewDecoder(resp.Body).Decode(&folders); err != nil {
+		logging.L().Error("failed to parse artifact folder response", "folder", folderID, "error", err)
 		return
 	}
 
 	for _, folder := range folders {
-		node := FileNode{
-			ID:         folder.ID,
-			IsFile:     folder.IsFile,
-			Name:       folder.Name,
-			Downloaded: false,
-		}
-		*fileTree = append(*fileTree, node)
-
 		if !folder.IsFile {
 			wg.Add(1)
-			go func(fID string) {
-				defer wg.Done()
-				traverseAndStoreFileTree(host, token, fID, fileTree, wg, sem)
-			}(folder.ID)
+			go listFolder(ctx, host, token, folder.ID, files, progress, wg, sem)
+			continue
+		}
+		atomic.AddInt64(&progress.seen, 1)
+		select {
+		case files <- fileDescriptor{ID: folder.ID}:
+		case <-ctx.Done():
+			return
 		}
 	}
 }
 
-func downloadFileWithRetry(host string, token string, fileNode *FileNode, whereToSave string, sem chan struct{}, errors chan<- error, maxRetries int) {
-    var err error
-    for i := 0; i < maxRetries; i++ {
-        sem <- struct{}{} // Acquire semaphore
-        err = downloadFile(host, token, fileNode.ID, whereToSave)
-        <-sem // Release semaphore
-
-        if err == nil {
-            fileNode.Downloaded = true
-            return
-        }
-
-        // Exponential backoff
-        time.Sleep(time.Duration(i) * time.Second)
-    }
-    errors <- err // Send error to error channel if all retries fail
+// printProgressEverySecond prints an aggregate "files completed / bytes
+// downloaded" line once a second until stop is closed, then prints a final
+// line and closes done.
+func printProgressEverySecond(progress *downloadStats, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			printProgressLine(progress)
+		case <-stop:
+			printProgressLine(progress)
+			fmt.Println()
+			return
+		}
+	}
+}
+
+func printProgressLine(progress *downloadStats) {
+	completed := atomic.LoadInt64(&progress.completed)
+	seen := atomic.LoadInt64(&progress.seen)
+	bytes := atomic.LoadInt64(&progress.bytes)
+	fmt.Printf("\rfiles: %d/%d completed, %s downloaded", completed, seen, humanBytes(bytes))
+}
+
+func humanBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// downloadFileWithRetry downloads fileID, retrying transient failures with
+// linear backoff, making at most maxRetries attempts in all. When sink is non-nil, the file is
+// mirrored to it inline with the download instead of afterward - see
+// downloadFileResumable.
+func downloadFileWithRetry(ctx context.Context, host string, token string, fileID string, whereToSave string, runId string, sink Sink, streamed *sync.Map, maxRetries int, progress *downloadStats) error {
+	var err error
+	for i := 0; i < maxRetries; i++ {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if i > 0 {
+			logging.L().Warn("retrying artifact download", "file", fileID, "attempt", i, "maxRetries", maxRetries, "error", err)
+			select {
+			case <-time.After(time.Duration(i) * time.Second):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		err = downloadFileResumable(ctx, host, token, fileID, whereToSave, runId, sink, streamed, progress)
+		if err == nil {
+			return nil
+		}
+	}
+	return err
 }
 
-func downloadFilesAndCheckForNew(host string, token string, runId string, fileTree *[]FileNode, whereToSave string, sem chan struct{}, errors chan<- error, maxRetries int) bool {
-    newFilesAdded := false
-    var notDownloadedCount int
-    var wg sync.WaitGroup
-
-    // Retry downloading for files that failed in previous attempts
-    for i := range *fileTree {
-        if (*fileTree)[i].IsFile && !(*fileTree)[i].Downloaded {
-            newFilesAdded = true
-            wg.Add(1)
-            go func(node *FileNode) {
-                defer wg.Done()
-                downloadFileWithRetry(host, token, node, whereToSave, sem, errors, maxRetries)
-            }(&(*fileTree)[i])
-        }
-    }
-    wg.Wait()
-
-    // Re-traverse the file tree to check for new files
-    var newFileTree []FileNode
-    traverseAndStoreFileTree(host, token, runId, &newFileTree, &wg, sem)
-    wg.Wait() // Wait for re-traversal to complete
-
-    // Check for new files and add them to the fileTree
-    for _, newNode := range newFileTree {
-        found := false
-        for _, existingNode := range *fileTree {
-            if newNode.ID == existingNode.ID {
-                found = true
-                break
-            }
-        }
-        if !found {
-            newFilesAdded = true
-            notDownloadedCount++
-            *fileTree = append(*fileTree, newNode)
-        }
-    }
-  
-    fmt.Printf("Number of files not yet downloaded: %d\n", notDownloadedCount)
-    return newFilesAdded
+// headContentLength issues a HEAD request for fileID and returns the
+// server-reported size, or -1 if the server didn't send one.
+func headContentLength(ctx context.Context, host string, token string, fileID string) (int64, error) {
+	validFileID := strings.ReplaceAll(fileID, "#", "%23")
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, "https://"+host+"/api/v1/artifact?key="+validFileID, nil)
+	if err != nil {
+		return -1, fmt.Errorf("failed to build HEAD request for %s: %w", fileID, err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := request.Do(ctx, req)
+	if err != nil {
+		return -1, fmt.Errorf("HEAD request for %s failed: %w", fileID, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return -1, fmt.Errorf("HEAD request for %s returned status %d", fileID, resp.StatusCode)
+	}
+	return resp.ContentLength, nil
 }
 
-func downloadFile(host string, token string, fileID string, whereToSave string) error {
+// downloadFileResumable downloads fileID into whereToSave. It first HEADs
+// the file to learn its size: if a same-named local file already matches
+// that size, the download is skipped outright. Otherwise it issues a Range
+// request continuing from wherever the local file (if any) left off. If
+// the server's Content-Length and the number of bytes actually written
+// disagree - e.g. a connection dropped mid-copy - the partial file is
+// removed so the caller's retry starts clean instead of keeping a silently
+// truncated file, which plain io.Copy would do.
+//
+// When sink is non-nil and this is a fresh (non-resumed) download, the
+// response body is teed to sink.Put concurrently with the local copy via an
+// io.Pipe, so the file reaches the sink as it streams in rather than only
+// after it's fully staged on disk; its sha256 is then recorded in streamed
+// under its post-relocation key so mirrorToSink doesn't re-upload it from
+// disk. Resumed and already-complete files aren't streamed this way and
+// fall to mirrorToSink's post-download pass instead.
+func downloadFileResumable(ctx context.Context, host string, token string, fileID string, whereToSave string, runId string, sink Sink, streamed *sync.Map, progress *downloadStats) error {
 	if fileID == "" {
 		return fmt.Errorf("empty fileID provided")
 	}
 
-	// Split the fileID path to figure out the folder structure and file name.
 	keyArray := strings.Split(fileID, "/")
 	subFolder := ""
 	if len(keyArray) > 1 {
@@ -188,29 +383,106 @@ func downloadFile(host string, token string, fileID string, whereToSave string)
 	fileName := keyArray[len(keyArray)-1]
 	fileFolder := path.Join(whereToSave, subFolder)
 
-	// Ensure the directory structure exists.
-	err := os.MkdirAll(fileFolder, os.ModePerm)
+	if err := os.MkdirAll(fileFolder, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	filePath := path.Join(fileFolder, fileName)
+
+	remoteSize, err := headContentLength(ctx, host, token, fileID)
 	if err != nil {
-		return fmt.Errorf("failed to create directory: %v", err)
+		return err
+	}
+
+	var existing int64
+	if info, statErr := os.Stat(filePath); statErr == nil {
+		existing = info.Size()
+	}
+	if remoteSize >= 0 && existing == remoteSize {
+		return nil // already fully downloaded
 	}
 
-	// Replace any '#' in the fileID with '%23' for the URL request. This is URL encoding.
 	validFileID := strings.ReplaceAll(fileID, "#", "%23")
-	resp := request.SendGetRequest("https://"+host+"/api/v1/artifact?key="+validFileID, token)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://"+host+"/api/v1/artifact?key="+validFileID, nil)
+	if err != nil {
+		return fmt.Errorf("failed to build request for %s: %w", fileID, err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	flags := os.O_CREATE | os.O_WRONLY
+	if existing > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", existing))
+		flags |= os.O_APPEND
+	} else {
+		flags |= os.O_TRUNC
+	}
+
+	resp, err := request.Do(ctx, req)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", fileID, err)
+	}
 	defer resp.Body.Close()
 
-	// Create the file at the determined path.
-	filePath := path.Join(fileFolder, fileName)
-	out, err := os.Create(filePath)
+	if existing > 0 && resp.StatusCode != http.StatusPartialContent {
+		// Server ignored the Range request (e.g. no resume support for this
+		// file) - fall back to downloading it from scratch.
+		existing = 0
+		flags = os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	} else if existing == 0 && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("download of %s returned status %d", fileID, resp.StatusCode)
+	}
+
+	out, err := os.OpenFile(filePath, flags, 0644)
 	if err != nil {
-		return fmt.Errorf("got error while os.Create: %v", err)
+		return fmt.Errorf("failed to open %s: %w", filePath, err)
 	}
 	defer out.Close()
 
-	// Copy the response body (the downloaded data) to our file.
-	_, err = io.Copy(out, resp.Body)
+	streamToSink := sink != nil && existing == 0
+	var key string
+	var body io.Reader = resp.Body
+	var pw *io.PipeWriter
+	var sinkResult chan error
+	if streamToSink {
+		key = strings.TrimPrefix(fileID, runId+"/")
+		var pr *io.PipeReader
+		pr, pw = io.Pipe()
+		body = io.TeeReader(resp.Body, pw)
+		sinkResult = make(chan error, 1)
+		go func() {
+			sum, sinkErr := sink.Put(ctx, key, pr)
+			if sinkErr != nil {
+				pr.CloseWithError(sinkErr)
+				sinkResult <- sinkErr
+				return
+			}
+			streamed.Store(key, sum)
+			sinkResult <- nil
+		}()
+	}
+
+	written, err := io.Copy(out, body)
+	var sinkErr error
+	if streamToSink {
+		if err != nil {
+			pw.CloseWithError(err)
+		} else {
+			pw.Close()
+		}
+		sinkErr = <-sinkResult
+	}
 	if err != nil {
-		return fmt.Errorf("error writing file: %v", err)
+		return fmt.Errorf("failed streaming %s: %w", fileID, err)
+	}
+	if sinkErr != nil {
+		return fmt.Errorf("failed to mirror %s to sink: %w", fileID, sinkErr)
+	}
+	atomic.AddInt64(&progress.bytes, written)
+
+	if resp.ContentLength >= 0 && written != resp.ContentLength {
+		os.Remove(filePath)
+		if streamToSink {
+			streamed.Delete(key)
+		}
+		return fmt.Errorf("downloaded %d bytes for %s, server reported %d: retrying", written, fileID, resp.ContentLength)
 	}
 
 	return nil
@@ -219,17 +491,17 @@ func downloadFile(host string, token string, fileID string, whereToSave string)
 func relocateContents(whereToSave string, runId string) {
 	runIdDir := filepath.Join(whereToSave, runId)
 	if _, err := os.Stat(runIdDir); os.IsNotExist(err) {
-		fmt.Println(runId, "directory does not exist. Skipping relocation.")
+		logging.L().Debug("run directory does not exist, skipping relocation", "dir", runIdDir)
 		return
 	}
 	if err := copy.Copy(runIdDir, whereToSave); err != nil {
-		fmt.Println("Error copying files:", err)
+		logging.L().Error("failed to relocate downloaded artifacts", "dir", runIdDir, "error", err)
 		return
 	}
 
 	// Remove the runId directory
 	if err := os.RemoveAll(runIdDir); err != nil {
-		fmt.Println("Error removing directory", runIdDir, ":", err)
+		logging.L().Error("failed to remove relocated run directory", "dir", runIdDir, "error", err)
 	}
 }
 
@@ -250,7 +522,7 @@ func updateJsonPaths(whereToSave string) {
 	})
 
 	if err != nil {
-		fmt.Println("Error walking the path", whereToSave, ":", err)
+		logging.L().Error("failed to walk artifact directory", "dir", whereToSave, "error", err)
 		return
 	}
 
@@ -258,56 +530,80 @@ func updateJsonPaths(whereToSave string) {
 	allureResultsDir := filepath.Join(whereToSave, "report", "allure-results")
 	files, err := ioutil.ReadDir(allureResultsDir)
 	if err != nil {
-		fmt.Println("Error reading directory", allureResultsDir, ":", err)
+		logging.L().Error("failed to read allure results directory", "dir", allureResultsDir, "error", err)
 		return
 	}
 
 	for _, file := range files {
-		if filepath.Ext(file.Name()) == ".json" {
-			filePath := filepath.Join(allureResultsDir, file.Name())
-
-			data, err := ioutil.ReadFile(filePath)
-			if err != nil {
-				fmt.Println("Error reading file", filePath, ":", err)
-				continue
-			}
-
-			var jsonData map[string]interface{}
-			if err := json.Unmarshal(data, &jsonData); err != nil {
-				fmt.Println("Error unmarshaling JSON data from file", filePath, ":", err)
-				continue
-			}
-
-			if attachments, ok := jsonData["attachments"].([]interface{}); ok {
-				for _, attachment := range attachments {
-					if attachMap, ok := attachment.(map[string]interface{}); ok {
-						if source, exists := attachMap["source"]; exists {
-							if sourceStr, ok := source.(string); ok {
-								filename := filepath.Base(sourceStr)
-
-								if newPath, found := fileMap[filename]; found {
-									relativePath, err := filepath.Rel(allureResultsDir, newPath)
-									if err != nil {
-										fmt.Println("Error calculating relative path for", newPath, ":", err)
-										continue
-									}
-									attachMap["source"] = relativePath
-								}
-							}
-						}
-					}
-				}
+		if filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+		filePath := filepath.Join(allureResultsDir, file.Name())
+		if err := rewriteAttachmentPaths(filePath, allureResultsDir, fileMap); err != nil {
+			logging.L().Error("failed to rewrite allure result", "file", filePath, "error", err)
+		}
+	}
+}
 
-				updatedData, err := json.MarshalIndent(jsonData, "", "  ")
-				if err != nil {
-					fmt.Println("Error marshaling JSON data for file", filePath, ":", err)
-					continue
-				}
+// rewriteAttachmentPaths points each attachment's "source" field in
+// filePath at where GetArtifacts actually saved that file (fileMap, keyed
+// by basename), streaming the file through a json.Decoder/Encoder pair
+// instead of buffering the whole thing via ioutil.ReadFile/WriteFile.
+func rewriteAttachmentPaths(filePath string, allureResultsDir string, fileMap map[string]string) error {
+	in, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", filePath, err)
+	}
+	var jsonData map[string]interface{}
+	decodeErr := json.NewDecoder(in).Decode(&jsonData)
+	in.Close()
+	if decodeErr != nil {
+		return fmt.Errorf("failed to unmarshal %s: %w", filePath, decodeErr)
+	}
 
-				if err := ioutil.WriteFile(filePath, updatedData, 0644); err != nil {
-					fmt.Println("Error writing updated data to file", filePath, ":", err)
-				}
-			}
+	attachments, ok := jsonData["attachments"].([]interface{})
+	if !ok {
+		return nil
+	}
+	for _, attachment := range attachments {
+		attachMap, ok := attachment.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		source, ok := attachMap["source"].(string)
+		if !ok {
+			continue
 		}
+		newPath, found := fileMap[filepath.Base(source)]
+		if !found {
+			continue
+		}
+		relativePath, err := filepath.Rel(allureResultsDir, newPath)
+		if err != nil {
+			return fmt.Errorf("failed to compute relative attachment path for %s: %w", newPath, err)
+		}
+		attachMap["source"] = relativePath
+	}
+
+	tmpPath := filePath + ".tmp"
+	out, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", tmpPath, err)
 	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	encodeErr := enc.Encode(jsonData)
+	closeErr := out.Close()
+	if encodeErr != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to marshal %s: %w", filePath, encodeErr)
+	}
+	if closeErr != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write %s: %w", tmpPath, closeErr)
+	}
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", filePath, err)
+	}
+	return nil
 }
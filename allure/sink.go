@@ -0,0 +1,351 @@
+package allure
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+
+	"cli/logging"
+
+	"cloud.google.com/go/storage"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+)
+
+// Manifest summarizes a completed artifact mirror. It is marshaled to
+// manifest.json and uploaded to the sink once all downloads finish.
+type Manifest struct {
+	RunID     string            `json:"run_id"`
+	Passed    int64             `json:"passed"`
+	Failed    int64             `json:"failed"`
+	Ignored   int64             `json:"ignored"`
+	Artifacts map[string]string `json:"artifacts"` // key (relative path) -> sha256
+}
+
+// Sink receives artifact bytes as they stream off the wire, so GetArtifacts
+// can mirror a run's Allure results into durable storage in addition to the
+// local whereToSave directory.
+type Sink interface {
+	// Put streams an artifact under key (its path relative to the run root)
+	// and returns the artifact's SHA-256, computed as it is written.
+	Put(ctx context.Context, key string, r io.Reader) (sha256Hex string, err error)
+	// Finalize uploads the manifest once every artifact has been written.
+	Finalize(ctx context.Context, manifest Manifest) error
+}
+
+// SinkConfig configures where GetArtifacts mirrors artifacts to, in addition
+// to whereToSave. An empty Provider disables mirroring.
+type SinkConfig struct {
+	Provider          string // "s3", "gcs", "azureblob", "zip", "local"
+	Bucket            string
+	Prefix            string
+	Region            string
+	CredentialsSource string // azureblob only: the storage account key; other providers ignore it and use their SDK's default credential chain
+	S3Endpoint        string // overrides the endpoint, e.g. for MinIO
+}
+
+// NewSink builds the Sink described by cfg, or returns (nil, nil) when no
+// provider is configured.
+func NewSink(ctx context.Context, cfg SinkConfig) (Sink, error) {
+	switch cfg.Provider {
+	case "":
+		return nil, nil
+	case "local":
+		return &LocalDirSink{dir: cfg.Bucket}, nil
+	case "s3":
+		return newS3Sink(ctx, cfg)
+	case "gcs":
+		return newGCSSink(ctx, cfg)
+	case "azureblob":
+		return newAzureBlobSink(ctx, cfg)
+	case "zip":
+		return newZipSink(cfg)
+	default:
+		return nil, fmt.Errorf("unknown artifact sink provider: %s", cfg.Provider)
+	}
+}
+
+// LocalDirSink mirrors artifacts into a second local directory, e.g. to copy
+// a run's results somewhere outside the --o output folder.
+type LocalDirSink struct {
+	dir string
+}
+
+func (l *LocalDirSink) Put(ctx context.Context, key string, r io.Reader) (string, error) {
+	dest := filepath.Join(l.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(dest), os.ModePerm); err != nil {
+		return "", fmt.Errorf("failed to create directory for %s: %w", dest, err)
+	}
+	out, err := os.Create(dest)
+	if err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", dest, err)
+	}
+	defer out.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(out, io.TeeReader(r, h)); err != nil {
+		return "", fmt.Errorf("failed to mirror %s: %w", key, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (l *LocalDirSink) Finalize(ctx context.Context, manifest Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	dest := filepath.Join(l.dir, "manifest.json")
+	if err := os.MkdirAll(filepath.Dir(dest), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", dest, err)
+	}
+	if err := os.WriteFile(dest, data, 0644); err != nil {
+		return fmt.Errorf("failed to write manifest to %s: %w", dest, err)
+	}
+	logging.L().Info("wrote artifact manifest", "sink", "local", "path", dest)
+	return nil
+}
+
+func withPrefix(prefix string, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return path.Join(prefix, key)
+}
+
+// S3Sink mirrors artifacts to an S3 (or S3-compatible, e.g. MinIO) bucket.
+type S3Sink struct {
+	client   *s3.Client
+	uploader *manager.Uploader
+	bucket   string
+	prefix   string
+}
+
+func newS3Sink(ctx context.Context, cfg SinkConfig) (*S3Sink, error) {
+	opts := []func(*awsconfig.LoadOptions) error{}
+	if cfg.Region != "" {
+		opts = append(opts, awsconfig.WithRegion(cfg.Region))
+	}
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	client := s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		if cfg.S3Endpoint != "" {
+			o.BaseEndpoint = aws.String(cfg.S3Endpoint)
+			o.UsePathStyle = true
+		}
+	})
+	return &S3Sink{client: client, uploader: manager.NewUploader(client), bucket: cfg.Bucket, prefix: cfg.Prefix}, nil
+}
+
+// Put streams r straight into S3 via manager.Uploader, which chunks it into
+// a multipart upload internally - an artifact never needs to fit in memory
+// or on local disk first, which matters for multi-GB attachments on
+// disk-constrained CI runners. Its sha256 is computed as a side effect of
+// the upload's own read, like the other sinks.
+func (s *S3Sink) Put(ctx context.Context, key string, r io.Reader) (string, error) {
+	h := sha256.New()
+	objectKey := withPrefix(s.prefix, key)
+	_, err := s.uploader.Upload(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(objectKey),
+		Body:   io.TeeReader(r, h),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload %s to s3://%s/%s: %w", key, s.bucket, objectKey, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (s *S3Sink) Finalize(ctx context.Context, manifest Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	key := withPrefix(s.prefix, "manifest.json")
+	_, err = s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(data),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload manifest to s3://%s/%s: %w", s.bucket, key, err)
+	}
+	logging.L().Info("uploaded artifact manifest", "sink", "s3", "bucket", s.bucket, "key", key)
+	return nil
+}
+
+// GCSSink mirrors artifacts to a Google Cloud Storage bucket.
+type GCSSink struct {
+	client *storage.Client
+	bucket string
+	prefix string
+}
+
+func newGCSSink(ctx context.Context, cfg SinkConfig) (*GCSSink, error) {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCS client: %w", err)
+	}
+	return &GCSSink{client: client, bucket: cfg.Bucket, prefix: cfg.Prefix}, nil
+}
+
+func (g *GCSSink) Put(ctx context.Context, key string, r io.Reader) (string, error) {
+	objectKey := withPrefix(g.prefix, key)
+	w := g.client.Bucket(g.bucket).Object(objectKey).NewWriter(ctx)
+	h := sha256.New()
+	if _, err := io.Copy(w, io.TeeReader(r, h)); err != nil {
+		w.Close()
+		return "", fmt.Errorf("failed to upload %s to gs://%s/%s: %w", key, g.bucket, objectKey, err)
+	}
+	if err := w.Close(); err != nil {
+		return "", fmt.Errorf("failed to finish upload of %s to gs://%s/%s: %w", key, g.bucket, objectKey, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (g *GCSSink) Finalize(ctx context.Context, manifest Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	key := withPrefix(g.prefix, "manifest.json")
+	w := g.client.Bucket(g.bucket).Object(key).NewWriter(ctx)
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return fmt.Errorf("failed to upload manifest to gs://%s/%s: %w", g.bucket, key, err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to finish manifest upload to gs://%s/%s: %w", g.bucket, key, err)
+	}
+	logging.L().Info("uploaded artifact manifest", "sink", "gcs", "bucket", g.bucket, "key", key)
+	return nil
+}
+
+// AzureBlobSink mirrors artifacts to an Azure Blob Storage container.
+type AzureBlobSink struct {
+	client    *azblob.Client
+	container string
+	prefix    string
+}
+
+func newAzureBlobSink(ctx context.Context, cfg SinkConfig) (*AzureBlobSink, error) {
+	accountURL := fmt.Sprintf("https://%s.blob.core.windows.net/", cfg.Region)
+	cred, err := azblob.NewSharedKeyCredential(cfg.Region, cfg.CredentialsSource)
+	var client *azblob.Client
+	if err != nil || cfg.CredentialsSource == "" {
+		client, err = azblob.NewClientWithNoCredential(accountURL, nil)
+	} else {
+		client, err = azblob.NewClientWithSharedKeyCredential(accountURL, cred, nil)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Azure Blob client: %w", err)
+	}
+	return &AzureBlobSink{client: client, container: cfg.Bucket, prefix: cfg.Prefix}, nil
+}
+
+// Put streams r straight into UploadStream, which already accepts an
+// io.Reader and chunks it into blocks internally - no need to buffer the
+// artifact in memory first. Its sha256 is computed as a side effect of the
+// upload's own read, like the other sinks.
+func (a *AzureBlobSink) Put(ctx context.Context, key string, r io.Reader) (string, error) {
+	h := sha256.New()
+	blobName := withPrefix(a.prefix, key)
+	_, err := a.client.UploadStream(ctx, a.container, blobName, io.TeeReader(r, h), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload %s to azureblob://%s/%s: %w", key, a.container, blobName, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (a *AzureBlobSink) Finalize(ctx context.Context, manifest Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	blobName := withPrefix(a.prefix, "manifest.json")
+	_, err = a.client.UploadBuffer(ctx, a.container, blobName, data, nil)
+	if err != nil {
+		return fmt.Errorf("failed to upload manifest to azureblob://%s/%s: %w", a.container, blobName, err)
+	}
+	logging.L().Info("uploaded artifact manifest", "sink", "azureblob", "container", a.container, "key", blobName)
+	return nil
+}
+
+// ZipSink mirrors artifacts into a single zip archive, e.g. for a `zip://`
+// -o destination. zip.Writer only supports one open entry at a time, so
+// Put and Finalize serialize on mu. Since GetArtifacts tees each fresh
+// download into Put through a pipe, downloads mirrored to a ZipSink wait on
+// that lock and so proceed one at a time.
+type ZipSink struct {
+	mu   sync.Mutex
+	file *os.File
+	zw   *zip.Writer
+	path string
+}
+
+func newZipSink(cfg SinkConfig) (*ZipSink, error) {
+	if err := os.MkdirAll(filepath.Dir(cfg.Bucket), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create directory for %s: %w", cfg.Bucket, err)
+	}
+	f, err := os.Create(cfg.Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", cfg.Bucket, err)
+	}
+	return &ZipSink{file: f, zw: zip.NewWriter(f), path: cfg.Bucket}, nil
+}
+
+func (z *ZipSink) Put(ctx context.Context, key string, r io.Reader) (string, error) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	w, err := z.zw.Create(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to add %s to %s: %w", key, z.path, err)
+	}
+	h := sha256.New()
+	if _, err := io.Copy(w, io.TeeReader(r, h)); err != nil {
+		return "", fmt.Errorf("failed to write %s to %s: %w", key, z.path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (z *ZipSink) Finalize(ctx context.Context, manifest Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+
+	z.mu.Lock()
+	w, createErr := z.zw.Create("manifest.json")
+	if createErr == nil {
+		_, createErr = w.Write(data)
+	}
+	closeErr := z.zw.Close()
+	z.mu.Unlock()
+	if createErr != nil {
+		return fmt.Errorf("failed to write manifest to %s: %w", z.path, createErr)
+	}
+	if closeErr != nil {
+		return fmt.Errorf("failed to finalize %s: %w", z.path, closeErr)
+	}
+	if err := z.file.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", z.path, err)
+	}
+	logging.L().Info("wrote artifact manifest", "sink", "zip", "path", z.path)
+	return nil
+}
@@ -11,6 +11,19 @@ import (
 
 var config *viper.Viper
 
+// stringSliceFlag accumulates repeated occurrences of a flag, e.g.
+// -report-format junit -report-format github, into a slice.
+type stringSliceFlag []string
+
+func (s *stringSliceFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringSliceFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
 func ReadFlags() error {
 	config = viper.New()
 
@@ -35,6 +48,24 @@ func ReadFlags() error {
 	CONFIG_SYSTEM_IMAGE := flag.String("system-image", "", "OS-specific system image. For Android one of [default,google_apis]. For iOS only [default]")
 	CONFIG_FILTER_FILE := flag.String("filter-file", "", "File containing test filters in YAML format, following the schema described at https://docs.marathonlabs.io/runner/configuration/filtering/#filtering-logic. For iOS see also https://docs.marathonlabs.io/runner/next/ios#test-plans.")
   CONFIG_FLAVOR := flag.String("flavor", "", "Type of tests to run. Default: [native]. Possible values: [native, js-test-appium, python-robotframework-appium]")
+  CONFIG_SILENT := flag.Bool("silent", false, "Suppress progress bars and periodic status updates")
+  CONFIG_NO_PROGRESS := flag.Bool("no-progress", false, "Disable progress bars, keeping other status output")
+  CONFIG_VERBOSE := flag.Bool("verbose", false, "Enable debug-level logging")
+  CONFIG_LOG_JSON := flag.Bool("log-json", false, "Emit logs as JSON instead of plain text")
+  CONFIG_ARTIFACT_SINK := flag.String("artifact-sink", "", "Mirror downloaded Allure artifacts to additional storage. One of [s3, gcs, azureblob, local]. Default: disabled")
+  CONFIG_ARTIFACT_SINK_BUCKET := flag.String("artifact-sink-bucket", "", "Bucket/container (or, for -artifact-sink=local, destination directory) to mirror artifacts to")
+  CONFIG_ARTIFACT_SINK_PREFIX := flag.String("artifact-sink-prefix", "", "Key prefix to mirror artifacts under")
+  CONFIG_ARTIFACT_SINK_REGION := flag.String("artifact-sink-region", "", "Region (S3/GCS) or storage account name (Azure Blob) for the artifact sink")
+  CONFIG_ARTIFACT_SINK_CREDENTIALS := flag.String("artifact-sink-credentials", "", "Credentials source for the artifact sink. For azureblob, the account key; other providers use their SDK's default credential chain")
+  CONFIG_ARTIFACT_SINK_S3_ENDPOINT := flag.String("artifact-sink-s3-endpoint", "", "Override the S3 endpoint, e.g. for MinIO")
+  var CONFIG_REPORT_FORMAT stringSliceFlag
+  flag.Var(&CONFIG_REPORT_FORMAT, "report-format", "Emit run results in this format in addition to -o. Repeatable. One of [junit, github, github-annotations, teamcity, json]")
+  CONFIG_REPORT_DIR := flag.String("report-dir", ".", "Directory to write junit/json report files to")
+  CONFIG_ALLOW_FAILURES := flag.Bool("allow-failures", false, "Exit 0 even when the run has failed tests. Applies to -api-key runs only; login/password runs exit non-zero unless the run state is passed")
+  CONFIG_QPS := flag.Float64("qps", 10, "Max requests per second to the API host, shared across retries and polling")
+  CONFIG_BURST := flag.Int("burst", 20, "Burst size for the --qps rate limiter")
+  CONFIG_MAX_ATTEMPTS := flag.Int("max-attempts", 5, "Max attempts for API requests that fail with a 5xx, 429 or transport error")
+  CONFIG_DOWNLOAD_CONCURRENCY := flag.Int("download-concurrency", 10, "Number of Allure artifacts to download in parallel")
 
 	args := os.Args
 	if len(args) > 1 && args[1] == "help" {
@@ -102,6 +133,23 @@ func ReadFlags() error {
 	config.Set("SYSTEM_IMAGE", *CONFIG_SYSTEM_IMAGE)
 	config.Set("FILTER_FILE", *CONFIG_FILTER_FILE)
 	config.Set("FLAVOR", *CONFIG_FLAVOR)
+	config.Set("SILENT", *CONFIG_SILENT)
+	config.Set("NO_PROGRESS", *CONFIG_NO_PROGRESS)
+	config.Set("VERBOSE", *CONFIG_VERBOSE)
+	config.Set("LOG_JSON", *CONFIG_LOG_JSON)
+	config.Set("ARTIFACT_SINK", *CONFIG_ARTIFACT_SINK)
+	config.Set("ARTIFACT_SINK_BUCKET", *CONFIG_ARTIFACT_SINK_BUCKET)
+	config.Set("ARTIFACT_SINK_PREFIX", *CONFIG_ARTIFACT_SINK_PREFIX)
+	config.Set("ARTIFACT_SINK_REGION", *CONFIG_ARTIFACT_SINK_REGION)
+	config.Set("ARTIFACT_SINK_CREDENTIALS", *CONFIG_ARTIFACT_SINK_CREDENTIALS)
+	config.Set("ARTIFACT_SINK_S3_ENDPOINT", *CONFIG_ARTIFACT_SINK_S3_ENDPOINT)
+	config.Set("REPORT_FORMAT", []string(CONFIG_REPORT_FORMAT))
+	config.Set("REPORT_DIR", *CONFIG_REPORT_DIR)
+	config.Set("ALLOW_FAILURES", *CONFIG_ALLOW_FAILURES)
+	config.Set("QPS", *CONFIG_QPS)
+	config.Set("BURST", *CONFIG_BURST)
+	config.Set("MAX_ATTEMPTS", *CONFIG_MAX_ATTEMPTS)
+	config.Set("DOWNLOAD_CONCURRENCY", *CONFIG_DOWNLOAD_CONCURRENCY)
 
 	return nil
 }
@@ -109,3 +157,14 @@ func ReadFlags() error {
 func GetConfig() *viper.Viper {
 	return config
 }
+
+// IsTerminal reports whether f is attached to an interactive terminal. main
+// uses it to turn progress output off when output is redirected, e.g. into a
+// CI log file; no textual progress updates are printed in its place.
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return (info.Mode() & os.ModeCharDevice) != 0
+}
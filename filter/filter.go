@@ -3,7 +3,11 @@ package filter
 import (
     "encoding/json"
     "errors"
+    "fmt"
     "io/ioutil"
+    "path/filepath"
+    "sort"
+    "strings"
 
     "gopkg.in/yaml.v2"
 )
@@ -38,6 +42,16 @@ func ValidateYAMLAndConvertToJSON(filePath string) (string, error) {
         return "", err
     }
 
+    baseDir := filepath.Dir(filePath)
+    cache := make(map[string][]byte)
+
+    if err := resolveFileIncludes(config.FilteringConfig.Allowlist, baseDir, cache, map[string]bool{}); err != nil {
+        return "", err
+    }
+    if err := resolveFileIncludes(config.FilteringConfig.Blocklist, baseDir, cache, map[string]bool{}); err != nil {
+        return "", err
+    }
+
     if err := validateFilters(config.FilteringConfig.Allowlist); err != nil {
         return "", err
     }
@@ -100,9 +114,6 @@ func validateNonCompositionFilter(filter Filter) error {
     if len(filter.Values) > 0 {
         fieldsInitialized++
     }
-    if filter.File != "" {
-        return errors.New("the 'file' field is not supported. Please include all values directly in the YAML")
-    }
     if fieldsInitialized > 1 {
         return errors.New("only one of [regex, values] can be specified for type: " + filter.Type)
     }
@@ -111,3 +122,141 @@ func validateNonCompositionFilter(filter Filter) error {
     }
     return nil
 }
+
+// resolveFileIncludes walks filters looking for a File directive and splices
+// its contents in place: a newline-delimited list of values for regular
+// filters, or a nested YAML fragment of filters for compositions. File is
+// resolved relative to baseDir (the directory of the top-level config) and
+// may be a glob, in which case matches are read in sorted order and
+// concatenated. cache avoids re-reading a file that's included more than
+// once within the same ValidateYAMLAndConvertToJSON call; visited tracks the
+// current include chain so an include cycle is reported instead of
+// recursing forever.
+func resolveFileIncludes(filters *[]Filter, baseDir string, cache map[string][]byte, visited map[string]bool) error {
+    if filters == nil {
+        return nil
+    }
+
+    for i := range *filters {
+        filter := &(*filters)[i]
+
+        if filter.File != "" {
+            if err := resolveFilterFile(filter, baseDir, cache, visited); err != nil {
+                return err
+            }
+        }
+
+        if len(filter.Filters) > 0 {
+            if err := resolveFileIncludes(&filter.Filters, baseDir, cache, visited); err != nil {
+                return err
+            }
+        }
+    }
+    return nil
+}
+
+func resolveFilterFile(filter *Filter, baseDir string, cache map[string][]byte, visited map[string]bool) error {
+    pattern := filter.File
+    if !filepath.IsAbs(pattern) {
+        pattern = filepath.Join(baseDir, pattern)
+    }
+
+    matches, err := filepath.Glob(pattern)
+    if err != nil {
+        return fmt.Errorf("invalid 'file' pattern %q: %w", filter.File, err)
+    }
+    if len(matches) == 0 {
+        return fmt.Errorf("'file' pattern %q did not match any files", filter.File)
+    }
+    sort.Strings(matches)
+
+    if filter.Type == "composition" {
+        var fragment []Filter
+        for _, match := range matches {
+            filters, err := readFilterFragment(match, cache, visited)
+            if err != nil {
+                return err
+            }
+            fragment = append(fragment, filters...)
+        }
+        filter.Filters = append(filter.Filters, fragment...)
+    } else {
+        var values []string
+        for _, match := range matches {
+            fileValues, err := readValuesFile(match, cache)
+            if err != nil {
+                return err
+            }
+            values = append(values, fileValues...)
+        }
+        filter.Values = append(filter.Values, values...)
+    }
+
+    filter.File = ""
+    return nil
+}
+
+func readValuesFile(path string, cache map[string][]byte) ([]string, error) {
+    data, err := readIncludedFile(path, cache)
+    if err != nil {
+        return nil, err
+    }
+
+    var values []string
+    for _, line := range strings.Split(string(data), "\n") {
+        line = strings.TrimSpace(line)
+        if line == "" {
+            continue
+        }
+        values = append(values, line)
+    }
+    return values, nil
+}
+
+func readFilterFragment(path string, cache map[string][]byte, visited map[string]bool) ([]Filter, error) {
+    absPath, err := filepath.Abs(path)
+    if err != nil {
+        return nil, fmt.Errorf("failed to resolve 'file' %q: %w", path, err)
+    }
+    if visited[absPath] {
+        return nil, fmt.Errorf("include cycle detected at %q", absPath)
+    }
+
+    data, err := readIncludedFile(path, cache)
+    if err != nil {
+        return nil, err
+    }
+
+    var fragment []Filter
+    if err := yaml.Unmarshal(data, &fragment); err != nil {
+        return nil, fmt.Errorf("failed to parse filter fragment %q: %w", absPath, err)
+    }
+
+    visited[absPath] = true
+    err = resolveFileIncludes(&fragment, filepath.Dir(absPath), cache, visited)
+    delete(visited, absPath)
+    if err != nil {
+        return nil, err
+    }
+
+    return fragment, nil
+}
+
+func readIncludedFile(path string, cache map[string][]byte) ([]byte, error) {
+    absPath, err := filepath.Abs(path)
+    if err != nil {
+        return nil, fmt.Errorf("failed to resolve 'file' %q: %w", path, err)
+    }
+
+    if data, ok := cache[absPath]; ok {
+        return data, nil
+    }
+
+    data, err := ioutil.ReadFile(absPath)
+    if err != nil {
+        return nil, fmt.Errorf("failed to read included file %q: %w", absPath, err)
+    }
+
+    cache[absPath] = data
+    return data, nil
+}
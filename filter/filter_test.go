@@ -17,10 +17,69 @@ func TestValidateYAMLAndConvertToJSONFragmentation(t *testing.T) {
     }
 }
 
-func TestValidateYAMLAndConvertToJSONFileType(t *testing.T) {
-    _, err := ValidateYAMLAndConvertToJSON("./testdata/filetype.yaml")
-    if err == nil || err.Error() != "the 'file' field is not supported. Please include all values directly in the YAML" {
-        t.Errorf("ValidateYAMLAndConvertToJSON failed to detect file filter parameter")
+func TestValidateYAMLAndConvertToJSONFileValues(t *testing.T) {
+    jsonOutput, err := ValidateYAMLAndConvertToJSON("./testdata/filetype.yaml")
+    if err != nil {
+        t.Fatalf("ValidateYAMLAndConvertToJSON failed for ./testdata/filetype.yaml: %v", err)
+    }
+
+    var config Configuration
+    if err := json.Unmarshal([]byte(jsonOutput), &config); err != nil {
+        t.Fatalf("failed to unmarshal JSON output: %v", err)
+    }
+
+    allowlist := *config.FilteringConfig.Allowlist
+    if allowlist[0].File != "" {
+        t.Errorf("expected 'file' to be cleared once resolved, got %q", allowlist[0].File)
+    }
+    expected := []string{"com.example.FirstTest", "com.example.SecondTest"}
+    if !reflect.DeepEqual(allowlist[0].Values, expected) {
+        t.Errorf("expected values %v loaded from file, got %v", expected, allowlist[0].Values)
+    }
+}
+
+func TestValidateYAMLAndConvertToJSONFileGlob(t *testing.T) {
+    jsonOutput, err := ValidateYAMLAndConvertToJSON("./testdata/fileglob.yaml")
+    if err != nil {
+        t.Fatalf("ValidateYAMLAndConvertToJSON failed for ./testdata/fileglob.yaml: %v", err)
+    }
+
+    var config Configuration
+    if err := json.Unmarshal([]byte(jsonOutput), &config); err != nil {
+        t.Fatalf("failed to unmarshal JSON output: %v", err)
+    }
+
+    allowlist := *config.FilteringConfig.Allowlist
+    expected := []string{"com.example.FirstTest", "com.example.SecondTest", "com.example.ThirdTest"}
+    if !reflect.DeepEqual(allowlist[0].Values, expected) {
+        t.Errorf("expected values %v merged from glob matches, got %v", expected, allowlist[0].Values)
+    }
+}
+
+func TestValidateYAMLAndConvertToJSONFileComposition(t *testing.T) {
+    jsonOutput, err := ValidateYAMLAndConvertToJSON("./testdata/filecomposition.yaml")
+    if err != nil {
+        t.Fatalf("ValidateYAMLAndConvertToJSON failed for ./testdata/filecomposition.yaml: %v", err)
+    }
+
+    var config Configuration
+    if err := json.Unmarshal([]byte(jsonOutput), &config); err != nil {
+        t.Fatalf("failed to unmarshal JSON output: %v", err)
+    }
+
+    composition := (*config.FilteringConfig.Allowlist)[0]
+    if composition.File != "" {
+        t.Errorf("expected 'file' to be cleared once resolved, got %q", composition.File)
+    }
+    if len(composition.Filters) != 2 {
+        t.Fatalf("expected 2 filters spliced in from fragment file, got %d", len(composition.Filters))
+    }
+}
+
+func TestValidateYAMLAndConvertToJSONFileCycle(t *testing.T) {
+    _, err := ValidateYAMLAndConvertToJSON("./testdata/filecycle.yaml")
+    if err == nil || !strings.Contains(err.Error(), "include cycle detected") {
+        t.Errorf("ValidateYAMLAndConvertToJSON should have failed for an include cycle, got: %v", err)
     }
 }
 
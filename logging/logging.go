@@ -0,0 +1,34 @@
+// Package logging provides the leveled logger shared by package request and
+// package allure, replacing ad-hoc fmt.Println error reporting.
+package logging
+
+import (
+	"log/slog"
+	"os"
+)
+
+var logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+// Configure installs the process-wide logger. verbose enables debug-level
+// output (--verbose); jsonOutput switches to JSON records (--log-json)
+// for log aggregation in CI.
+func Configure(verbose bool, jsonOutput bool) {
+	level := slog.LevelInfo
+	if verbose {
+		level = slog.LevelDebug
+	}
+	opts := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	if jsonOutput {
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	} else {
+		handler = slog.NewTextHandler(os.Stderr, opts)
+	}
+	logger = slog.New(handler)
+}
+
+// L returns the shared logger.
+func L() *slog.Logger {
+	return logger
+}
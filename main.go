@@ -3,18 +3,59 @@ package main
 import (
 	"cli/allure"
 	"cli/config"
+	"cli/logging"
+	"cli/report"
 	"cli/request"
 	"cli/filter"
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 )
 
+// Exit codes. Sentinel request errors are mapped to distinct codes so CI
+// scripts can branch on failure kind instead of parsing stderr.
+const (
+	exitFlagsInvalid     = 7
+	exitFilterInvalid    = 8
+	exitUnauthorized     = 6
+	exitRunNotFound      = 9
+	exitUploadFailed     = 5
+	exitRunFailed        = 4
+	exitTestsNotPassed   = 3
+	exitReportInvalid    = 11
+	exitInterrupted      = 130 // conventional 128+SIGINT exit code
+)
+
+func exitCodeForError(err error) int {
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return exitInterrupted
+	case errors.Is(err, request.ErrUnauthorized):
+		return exitUnauthorized
+	case errors.Is(err, request.ErrRunNotFound):
+		return exitRunNotFound
+	case errors.Is(err, request.ErrUploadFailed):
+		return exitUploadFailed
+	default:
+		return exitRunFailed
+	}
+}
+
 func main() {
+	// A Ctrl-C / SIGTERM while a run is in flight cancels ctx, which
+	// unwinds the upload, run-wait and artifact-download loops below instead
+	// of leaving them to poll until the process is killed.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	err := config.ReadFlags()
 	if err != nil {
 		fmt.Println("Error reading flags:\n", err.Error())
-		os.Exit(7)
+		os.Exit(exitFlagsInvalid)
 	}
 
 	conf := config.GetConfig()
@@ -32,64 +73,200 @@ func main() {
 	isolated := conf.GetString("ISOLATED")
 	systemImage := conf.GetString("SYSTEM_IMAGE")
 	filterFile := conf.GetString("FILTER_FILE")
+	flavor := conf.GetString("FLAVOR")
+	silent := conf.GetBool("SILENT")
+	noProgress := conf.GetBool("NO_PROGRESS")
+	verbose := conf.GetBool("VERBOSE")
+	logJSON := conf.GetBool("LOG_JSON")
+	artifactSinkProvider := conf.GetString("ARTIFACT_SINK")
+	artifactSinkBucket := conf.GetString("ARTIFACT_SINK_BUCKET")
+	artifactSinkPrefix := conf.GetString("ARTIFACT_SINK_PREFIX")
+	artifactSinkRegion := conf.GetString("ARTIFACT_SINK_REGION")
+	artifactSinkCredentials := conf.GetString("ARTIFACT_SINK_CREDENTIALS")
+	artifactSinkS3Endpoint := conf.GetString("ARTIFACT_SINK_S3_ENDPOINT")
+	reportDir := conf.GetString("REPORT_DIR")
+	allowFailures := conf.GetBool("ALLOW_FAILURES")
+	qps := conf.GetFloat64("QPS")
+	burst := conf.GetInt("BURST")
+	maxAttempts := conf.GetInt("MAX_ATTEMPTS")
+	downloadConcurrency := conf.GetInt("DOWNLOAD_CONCURRENCY")
+
+	logging.Configure(verbose, logJSON)
+
+	clientOpts := request.DefaultClientOptions()
+	clientOpts.QPS = qps
+	clientOpts.Burst = burst
+	clientOpts.MaxAttempts = maxAttempts
+	request.ConfigureClient(clientOpts)
+
+	var reportFormats []report.Format
+	for _, f := range conf.GetStringSlice("REPORT_FORMAT") {
+		format, err := report.ParseFormat(f)
+		if err != nil {
+			fmt.Println("Error reading flags:\n", err.Error())
+			os.Exit(exitReportInvalid)
+		}
+		reportFormats = append(reportFormats, format)
+	}
+
+	showProgress := !silent && !noProgress && config.IsTerminal(os.Stderr)
+	request.ShowProgress = showProgress
+	allure.ShowProgress = showProgress
+	allure.DownloadConcurrency = downloadConcurrency
+
+	artifactSink, err := allure.NewSink(ctx, allure.SinkConfig{
+		Provider:          artifactSinkProvider,
+		Bucket:            artifactSinkBucket,
+		Prefix:            artifactSinkPrefix,
+		Region:            artifactSinkRegion,
+		CredentialsSource: artifactSinkCredentials,
+		S3Endpoint:        artifactSinkS3Endpoint,
+	})
+	if err != nil {
+		fmt.Println("Error configuring artifact sink:", err.Error())
+		os.Exit(exitFlagsInvalid)
+	}
+
+	// -o also accepts a destination URL (s3://, gs://, zip://) instead of a
+	// plain local directory. Downloads still land in a local temp dir first -
+	// GetArtifacts' resumable downloads rely on that directory persisting
+	// across retries - which is mirrored to the sink and removed when main
+	// returns. The os.Exit paths below skip that removal and leave it in place.
+	if len(allureOutput) > 0 {
+		localDir, urlSinkCfg, remote, parseErr := allure.ParseOutputDestination(allureOutput)
+		if parseErr != nil {
+			fmt.Println(parseErr.Error())
+			os.Exit(exitFlagsInvalid)
+		}
+		if remote {
+			tempDir, tempErr := os.MkdirTemp("", "marathon-artifacts-*")
+			if tempErr != nil {
+				fmt.Println("Error creating temp directory for artifact download:", tempErr.Error())
+				os.Exit(exitFlagsInvalid)
+			}
+			defer os.RemoveAll(tempDir)
+			allureOutput = tempDir
+
+			if artifactSink == nil {
+				urlSinkCfg.S3Endpoint = artifactSinkS3Endpoint
+				artifactSink, err = allure.NewSink(ctx, urlSinkCfg)
+				if err != nil {
+					fmt.Println("Error configuring artifact sink:", err.Error())
+					os.Exit(exitFlagsInvalid)
+				}
+			}
+		} else {
+			allureOutput = localDir
+		}
+	}
 
   var filteringConfigJson = ""
 	if len(filterFile) == 0 {
-    filteringConfigJson, err = filter.ValidateYAMLAndConvertToJSON(filterFile) 
+    filteringConfigJson, err = filter.ValidateYAMLAndConvertToJSON(filterFile)
     if err != nil {
       fmt.Printf("Error happened attempting to read %s\n", filterFile)
 			fmt.Println(err.Error())
-			os.Exit(8)
+			os.Exit(exitFilterInvalid)
     }
   }
 
 	if len(apiKey) == 0 {
-		token, err := request.Authorize(login, password)
+		token, err := request.Authorize(ctx, host, login, password)
 		if err != nil {
 			fmt.Println("Can't login: ", err.Error())
-			os.Exit(6)
+			os.Exit(exitCodeForError(err))
 		}
 		fmt.Println(time.Now().Format(time.Stamp), "Creating new run")
-		runId, err := request.SendNewRun(token, app, testApp, commitName, commitLink, platform)
+		runId, err := request.SendNewRun(ctx, host, token, app, testApp, commitName, commitLink, platform)
 		if err != nil {
 			fmt.Println(err.Error())
-			os.Exit(5)
+			os.Exit(exitCodeForError(err))
 		}
-		go request.Subscribe(token, runId)
+		subscribeCtx, stopSubscribe := context.WithCancel(ctx)
+		states, subscribeErrs := request.Subscribe(subscribeCtx, token, runId)
+		go printRuntimeProgress(states, subscribeErrs)
 
-		state, err := request.WaitRunForEnd(runId, token)
+		state, stats, err := request.WaitRunForEnd(ctx, host, runId, token)
+		stopSubscribe()
 		if len(allureOutput) > 0 {
-			allure.GetArtifacts(token, runId, allureOutput)
+			if artifactErr := allure.GetArtifacts(ctx, host, token, runId, allureOutput, artifactSink, stats); artifactErr != nil {
+				fmt.Println(artifactErr.Error())
+			}
 		}
 		if err != nil {
 			fmt.Println(err.Error())
-			os.Exit(4)
+			os.Exit(exitCodeForError(err))
 		}
 		if state != "passed" {
-			os.Exit(3)
+			os.Exit(exitTestsNotPassed)
 		}
 	} else {
-		jwtToken, err := request.RequestJwtToken(apiKey)
+		jwtToken, err := request.RequestJwtToken(ctx, host, apiKey)
 		if err != nil {
 			fmt.Println(err)
-			return
+			os.Exit(exitCodeForError(err))
 		}
-		runId, err := request.SendNewRunWithKey(host, apiKey, app, testApp, commitName, commitLink, platform, osVersion, systemImage, isolated, filteringConfigJson)
+		runId, err := request.SendNewRunWithKey(ctx, host, apiKey, app, testApp, commitName, commitLink, platform, osVersion, systemImage, isolated, filteringConfigJson, flavor, nil)
 		if err != nil {
 			fmt.Println(err.Error())
-			os.Exit(5)
+			os.Exit(exitCodeForError(err))
 		}
-		go request.Subscribe(jwtToken, runId)
-		state, err := request.WaitRunForEndWithApiKey(runId, apiKey)
+		subscribeCtx, stopSubscribe := context.WithCancel(ctx)
+		states, subscribeErrs := request.Subscribe(subscribeCtx, jwtToken, runId)
+		go printRuntimeProgress(states, subscribeErrs)
+
+		_, stats, err := request.WaitRunForEndWithApiKey(ctx, host, runId, apiKey)
+		stopSubscribe()
 		if len(allureOutput) > 0 {
-			allure.GetArtifacts(jwtToken, runId, allureOutput)
+			if artifactErr := allure.GetArtifacts(ctx, host, jwtToken, runId, allureOutput, artifactSink, stats); artifactErr != nil {
+				fmt.Println(artifactErr.Error())
+			}
+		}
+		if len(reportFormats) > 0 {
+			tests, testsErr := request.GetTestResults(ctx, host, jwtToken, runId)
+			if testsErr != nil {
+				fmt.Println(testsErr.Error())
+			}
+			if reportErr := report.Emit(report.Run{RunID: runId, Stats: stats, Tests: tests}, reportFormats, reportDir); reportErr != nil {
+				fmt.Println(reportErr.Error())
+			}
 		}
 		if err != nil {
 			fmt.Println(err.Error())
-			os.Exit(4)
+			os.Exit(exitCodeForError(err))
 		}
-		if state != "passed" {
-			os.Exit(3)
+		if stats != nil && stats.Failed.Int64 > 0 && !allowFailures {
+			os.Exit(exitTestsNotPassed)
+		}
+	}
+}
+
+// printRuntimeProgress prints the live progress feed from request.Subscribe
+// until both channels are closed (the run ended or ctx was canceled),
+// draining whichever errs arrive without failing the run - a dropped
+// progress feed reconnects on its own and shouldn't affect the run outcome.
+func printRuntimeProgress(states <-chan request.RuntimeState, errs <-chan error) {
+	for states != nil || errs != nil {
+		select {
+		case s, ok := <-states:
+			if !ok {
+				states = nil
+				continue
+			}
+			if len(s.State) > 0 {
+				fmt.Println(time.Now().Format(time.Stamp), s.State)
+				continue
+			}
+			fmt.Printf("%s Running %d%% done\n", time.Now().Format(time.Stamp), s.Percents)
+			if len(s.TestName) > 0 {
+				fmt.Printf("%s %s %s \n", time.Now().Format(time.Stamp), s.TestName, s.TestState)
+			}
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			logging.L().Warn("runtime progress feed error", "error", err)
 		}
 	}
 }
@@ -0,0 +1,85 @@
+package report
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"cli/request"
+)
+
+// writeGithubSummary appends a run summary table to $GITHUB_STEP_SUMMARY. It
+// is a no-op outside GitHub Actions, where that variable is unset.
+func writeGithubSummary(run Run) error {
+	path := os.Getenv("GITHUB_STEP_SUMMARY")
+	if path == "" {
+		return nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "## Marathon Cloud run %s\n\n", run.RunID)
+	if run.Stats != nil {
+		fmt.Fprintf(&b, "| Passed | Failed | Ignored |\n")
+		fmt.Fprintf(&b, "|---|---|---|\n")
+		fmt.Fprintf(&b, "| %d | %d | %d |\n\n", run.Stats.Passed.Int64, run.Stats.Failed.Int64, run.Stats.Ignored.Int64)
+	}
+
+	var failed []request.TestResult
+	for _, t := range run.Tests {
+		if t.Status == "failed" {
+			failed = append(failed, t)
+		}
+	}
+	if len(failed) > 0 {
+		fmt.Fprintf(&b, "### Failed tests\n\n")
+		for _, t := range failed {
+			fmt.Fprintf(&b, "- **%s / %s**: %s\n", t.Suite, t.Name, t.FailureMessage)
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(b.String()); err != nil {
+		return fmt.Errorf("failed to write GitHub Actions summary: %w", err)
+	}
+	return nil
+}
+
+// writeGithubAnnotations prints a GitHub Actions error workflow command for
+// each failed test, so failures surface as inline annotations on the PR
+// diff instead of only in the step summary table written by
+// writeGithubSummary.
+func writeGithubAnnotations(run Run) {
+	for _, t := range run.Tests {
+		if t.Status != "failed" {
+			continue
+		}
+		fmt.Printf("::error file=%s,title=%s::%s\n", escapeWorkflowProperty(t.Suite), escapeWorkflowProperty(t.Name), escapeWorkflowData(t.FailureMessage))
+	}
+}
+
+// escapeWorkflowData escapes a workflow command's message (the part after
+// the final `::`), per
+// https://docs.github.com/en/actions/using-workflows/workflow-commands-for-github-actions#setting-an-error-message.
+// % must be escaped before \r/\n so a literal "%0A" in the input isn't
+// doubly interpreted as an escaped newline.
+func escapeWorkflowData(s string) string {
+	s = strings.ReplaceAll(s, "%", "%25")
+	s = strings.ReplaceAll(s, "\r", "%0D")
+	s = strings.ReplaceAll(s, "\n", "%0A")
+	return s
+}
+
+// escapeWorkflowProperty escapes a workflow command property value (e.g.
+// file=, title=), which on top of escapeWorkflowData's substitutions also
+// needs : and , escaped since those delimit properties.
+func escapeWorkflowProperty(s string) string {
+	s = escapeWorkflowData(s)
+	s = strings.ReplaceAll(s, ":", "%3A")
+	s = strings.ReplaceAll(s, ",", "%2C")
+	return s
+}
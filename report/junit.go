@@ -0,0 +1,103 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// junitTestSuites is the root element consumed by Jenkins' JUnit plugin and
+// GitLab's test report widget.
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	TimeSec  float64         `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	TimeSec   float64       `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Trace   string `xml:",chardata"`
+}
+
+type junitSkipped struct{}
+
+// writeJUnit converts run into JUnit XML and writes it to
+// <outDir>/junit.xml, creating outDir if necessary. Tests are grouped into
+// one testsuite per device, since a run spreads its tests across several
+// emulators/devices in parallel and per-device suites are what CI dashboards
+// expect to shard on; tests with no reported device fall back to a single
+// suite named after the run.
+func writeJUnit(run Run, outDir string) error {
+	suites := make(map[string]*junitTestSuite)
+	var order []string
+	suiteFor := func(device string) *junitTestSuite {
+		name := device
+		if name == "" {
+			name = run.RunID
+		}
+		if s, ok := suites[name]; ok {
+			return s
+		}
+		s := &junitTestSuite{Name: name}
+		suites[name] = s
+		order = append(order, name)
+		return s
+	}
+
+	for _, t := range run.Tests {
+		suite := suiteFor(t.Device)
+		tc := junitTestCase{
+			Name:      t.Name,
+			ClassName: t.Suite,
+			TimeSec:   float64(t.DurationMs) / 1000,
+		}
+		switch t.Status {
+		case "failed":
+			suite.Failures++
+			tc.Failure = &junitFailure{Message: t.FailureMessage, Trace: t.StackTrace}
+		case "ignored":
+			tc.Skipped = &junitSkipped{}
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+
+	result := junitTestSuites{}
+	for _, name := range order {
+		suite := suites[name]
+		suite.Tests = len(suite.Cases)
+		if run.Stats != nil {
+			suite.TimeSec = float64(run.Stats.TotalRunTime)
+		}
+		result.Suites = append(result.Suites, *suite)
+	}
+
+	out, err := xml.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JUnit report: %w", err)
+	}
+
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create report output dir %s: %w", outDir, err)
+	}
+	dest := filepath.Join(outDir, "junit.xml")
+	if err := os.WriteFile(dest, append([]byte(xml.Header), out...), 0o644); err != nil {
+		return fmt.Errorf("failed to write JUnit report to %s: %w", dest, err)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+// Package report emits a finished run's results in formats consumed by CI
+// systems, in addition to the Allure raw results downloaded by package
+// allure. It works whether or not the user requested -o, since its input is
+// RunStats and the per-test outcomes fetched via request.GetTestResults
+// rather than the Allure artifact tree.
+package report
+
+import (
+	"fmt"
+
+	"cli/request"
+)
+
+// Format identifies one of the supported output formats, selected by a
+// repeatable --report-format flag.
+type Format string
+
+const (
+	FormatJUnit             Format = "junit"
+	FormatGithub            Format = "github"
+	FormatGithubAnnotations Format = "github-annotations"
+	FormatTeamCity          Format = "teamcity"
+	FormatJSON              Format = "json"
+)
+
+// ParseFormat validates s as a Format, returning an error that lists the
+// supported values if it isn't one.
+func ParseFormat(s string) (Format, error) {
+	switch Format(s) {
+	case FormatJUnit, FormatGithub, FormatGithubAnnotations, FormatTeamCity, FormatJSON:
+		return Format(s), nil
+	default:
+		return "", fmt.Errorf("unknown report format %q, must be one of [junit, github, github-annotations, teamcity, json]", s)
+	}
+}
+
+// Run is the data a run emits through one or more formats: the aggregate
+// stats returned by WaitRunForEndWithApiKey plus the per-test outcomes
+// fetched separately, since RunStats alone has no per-test breakdown.
+type Run struct {
+	RunID string
+	Stats *request.RunStats
+	Tests []request.TestResult
+}
+
+// Emit writes run in every requested format, to destinations chosen by each
+// emitter (junit and json take outDir, github writes to $GITHUB_STEP_SUMMARY
+// when set, github-annotations and teamcity write to stdout). It returns the
+// first error encountered, having attempted every format regardless.
+func Emit(run Run, formats []Format, outDir string) error {
+	var firstErr error
+	for _, format := range formats {
+		var err error
+		switch format {
+		case FormatJUnit:
+			err = writeJUnit(run, outDir)
+		case FormatGithub:
+			err = writeGithubSummary(run)
+		case FormatGithubAnnotations:
+			writeGithubAnnotations(run)
+		case FormatTeamCity:
+			err = writeTeamCity(run)
+		case FormatJSON:
+			err = writeRunJSON(run, outDir)
+		default:
+			err = fmt.Errorf("unknown report format %q", format)
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,39 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"cli/request"
+)
+
+// runJSON is the machine-readable shape written to run.json, combining the
+// aggregate RunStats with the per-test breakdown in one file for scripts
+// that would otherwise have to call both endpoints themselves.
+type runJSON struct {
+	RunID string               `json:"run_id"`
+	Stats *request.RunStats    `json:"stats"`
+	Tests []request.TestResult `json:"tests"`
+}
+
+// writeRunJSON writes run as JSON to <outDir>/run.json, creating outDir if
+// necessary.
+func writeRunJSON(run Run, outDir string) error {
+	doc := runJSON{RunID: run.RunID, Stats: run.Stats, Tests: run.Tests}
+
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal run.json: %w", err)
+	}
+
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create report output dir %s: %w", outDir, err)
+	}
+	dest := filepath.Join(outDir, "run.json")
+	if err := os.WriteFile(dest, out, 0o644); err != nil {
+		return fmt.Errorf("failed to write run.json to %s: %w", dest, err)
+	}
+	return nil
+}
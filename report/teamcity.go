@@ -0,0 +1,50 @@
+package report
+
+import "fmt"
+
+// teamcityEscape escapes the characters TeamCity's service message format
+// requires, per
+// https://www.jetbrains.com/help/teamcity/service-messages.html#Escaped+Values.
+func teamcityEscape(s string) string {
+	out := make([]rune, 0, len(s))
+	for _, r := range s {
+		switch r {
+		case '|':
+			out = append(out, '|', '|')
+		case '\'':
+			out = append(out, '|', '\'')
+		case '\n':
+			out = append(out, '|', 'n')
+		case '\r':
+			out = append(out, '|', 'r')
+		case '[':
+			out = append(out, '|', '[')
+		case ']':
+			out = append(out, '|', ']')
+		default:
+			out = append(out, r)
+		}
+	}
+	return string(out)
+}
+
+// writeTeamCity prints the run as TeamCity service messages on stdout, so a
+// TeamCity build step running this CLI reports each test without a separate
+// parsing step.
+func writeTeamCity(run Run) error {
+	suiteName := teamcityEscape(run.RunID)
+	fmt.Printf("##teamcity[testSuiteStarted name='%s']\n", suiteName)
+	for _, t := range run.Tests {
+		name := teamcityEscape(t.Suite + "." + t.Name)
+		fmt.Printf("##teamcity[testStarted name='%s']\n", name)
+		switch t.Status {
+		case "failed":
+			fmt.Printf("##teamcity[testFailed name='%s' message='%s']\n", name, teamcityEscape(t.FailureMessage))
+		case "ignored":
+			fmt.Printf("##teamcity[testIgnored name='%s']\n", name)
+		}
+		fmt.Printf("##teamcity[testFinished name='%s' duration='%d']\n", name, t.DurationMs)
+	}
+	fmt.Printf("##teamcity[testSuiteFinished name='%s']\n", suiteName)
+	return nil
+}
@@ -0,0 +1,167 @@
+package request
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"cli/logging"
+
+	"golang.org/x/time/rate"
+)
+
+// ClientOptions configures the Client shared by every call this package
+// makes against the Marathon Cloud API.
+type ClientOptions struct {
+	// Timeout bounds a single HTTP round trip (connection + headers + body).
+	Timeout time.Duration
+	// QPS and Burst configure a token-bucket rate limiter shared across all
+	// requests, so parallel CI jobs hitting the same host don't get
+	// throttled by the server.
+	QPS   float64
+	Burst int
+	// MaxAttempts bounds retries on 5xx, 429 and transport errors.
+	MaxAttempts int
+}
+
+func DefaultClientOptions() ClientOptions {
+	return ClientOptions{
+		Timeout:     60 * time.Second,
+		QPS:         10,
+		Burst:       20,
+		MaxAttempts: 5,
+	}
+}
+
+// Client wraps *http.Client with the cross-cutting behaviour every call
+// into the API needs: a per-request timeout, a token-bucket rate limiter
+// and retry-with-backoff, replacing the http.Client{} built inline per
+// call. It is safe for concurrent use.
+type Client struct {
+	http        *http.Client
+	limiter     *rate.Limiter
+	maxAttempts int
+}
+
+func NewClient(opts ClientOptions) *Client {
+	return &Client{
+		http:        &http.Client{Timeout: opts.Timeout},
+		limiter:     rate.NewLimiter(rate.Limit(opts.QPS), opts.Burst),
+		maxAttempts: opts.MaxAttempts,
+	}
+}
+
+// defaultClient is the process-wide Client used by the package-level
+// request helpers below. ConfigureClient installs a differently-tuned one
+// (e.g. from --qps/--burst/--max-attempts), mirroring logging.Configure.
+var defaultClient = NewClient(DefaultClientOptions())
+
+// ConfigureClient installs the process-wide Client. It should be called
+// once from main, before the first request is made.
+func ConfigureClient(opts ClientOptions) {
+	defaultClient = NewClient(opts)
+}
+
+// isRetryableStatus reports whether statusCode represents a transient
+// server-side condition worth retrying.
+func isRetryableStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= 500
+}
+
+// retryAfter parses a Retry-After header (seconds, or an HTTP-date) and
+// reports how long to wait before the next attempt, if the server sent one.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(v); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if when, err := http.ParseTime(v); err == nil {
+		if d := time.Until(when); d > 0 {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// Do sends req, waiting for rate limiter capacity and retrying transient
+// failures (transport errors, 429, 5xx) with exponential backoff and
+// jitter, honoring a server Retry-After header when present. ctx
+// cancellation - e.g. Ctrl-C - aborts the in-flight attempt and the retry
+// loop immediately. req must carry a replayable body (nil, or one built by
+// http.NewRequestWithContext from a bytes/strings-backed reader, which
+// populates req.GetBody automatically) since a retry re-issues it; use
+// DoOnce for requests streamed from a pipe that can't be replayed.
+func (c *Client) Do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	var lastErr error
+	sleep := 500 * time.Millisecond
+	const maxSleep = 30 * time.Second
+
+	for attempt := 0; attempt < c.maxAttempts; attempt++ {
+		if attempt > 0 {
+			logging.L().Warn("retrying request", "url", req.URL.String(), "attempt", attempt, "maxAttempts", c.maxAttempts, "error", lastErr)
+			if err := sleepOrDone(ctx, sleep); err != nil {
+				return nil, err
+			}
+			sleep = backoffSleep(sleep*2, maxSleep)
+		}
+
+		attemptReq := req
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("failed to rewind request body for retry: %w", err)
+			}
+			clone := req.Clone(ctx)
+			clone.Body = body
+			attemptReq = clone
+		}
+
+		if err := c.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+
+		resp, err := c.http.Do(attemptReq)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			lastErr = err
+			continue
+		}
+
+		if !isRetryableStatus(resp.StatusCode) || attempt == c.maxAttempts-1 {
+			return resp, nil
+		}
+		if wait, ok := retryAfter(resp); ok {
+			sleep = wait
+		}
+		lastErr = fmt.Errorf("server returned status %d", resp.StatusCode)
+		resp.Body.Close()
+	}
+
+	return nil, fmt.Errorf("%w: %d attempts: %w", ErrRequestFailed, c.maxAttempts, lastErr)
+}
+
+// Do sends req through the process-wide Client, for callers outside this
+// package that need to issue a request SendGetRequest/sendPostRequest don't
+// cover (e.g. allure's HEAD/Range downloads).
+func Do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	return defaultClient.Do(ctx, req)
+}
+
+// DoOnce sends req once, applying the rate limiter but no retry - for
+// requests whose body is streamed from a pipe and so can't be replayed.
+// Callers that need retry-with-resume for these (e.g. SendNewRunWithKey)
+// implement it themselves at a higher level, where the upload can be
+// restarted from a known offset instead of replayed byte-for-byte.
+func (c *Client) DoOnce(ctx context.Context, req *http.Request) (*http.Response, error) {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	return c.http.Do(req)
+}
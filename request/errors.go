@@ -0,0 +1,12 @@
+package request
+
+import "errors"
+
+// Sentinel errors returned by package request so callers can branch on
+// failure kind with errors.Is instead of matching on message strings.
+var (
+	ErrUnauthorized  = errors.New("unauthorized")
+	ErrRunNotFound   = errors.New("run not found")
+	ErrUploadFailed  = errors.New("upload failed")
+	ErrRequestFailed = errors.New("request failed")
+)
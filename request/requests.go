@@ -2,9 +2,12 @@ package request
 
 import (
 	"bytes"
-	"errors"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
+	"math/rand"
 	"mime/multipart"
 	"net/http"
 	"os"
@@ -14,9 +17,17 @@ import (
 
 	"encoding/json"
 
+	"cli/logging"
+
+	"github.com/cheggaaa/pb/v3"
 	"gopkg.in/guregu/null.v4"
 )
 
+// ShowProgress controls whether upload progress bars are rendered while
+// streaming the app and test app. Disabled by --silent or --no-progress, or
+// automatically when stderr is not a TTY.
+var ShowProgress = true
+
 type Login struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
@@ -26,61 +37,65 @@ type LoginResponse struct {
 	Token string `json:"token"`
 }
 
-func Authorize(host string, login string, password string) (string, error) {
+func Authorize(ctx context.Context, host string, login string, password string) (string, error) {
 	authBody := Login{Email: login, Password: password}
 
 	reqBody, err := json.Marshal(authBody)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode auth request: %w", err)
+	}
 
-	resp := sendPostRequest("https://"+host+"/api/v1/cli/auth", &reqBody)
+	resp, err := sendPostRequest(ctx, "https://"+host+"/api/v1/cli/auth", &reqBody)
 	if err != nil {
-		fmt.Println("Error while creating auth json: ", err.Error())
+		return "", fmt.Errorf("failed to send auth request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", errors.New("Can't authorize")
+		return "", fmt.Errorf("%w: server returned status %d", ErrUnauthorized, resp.StatusCode)
 	}
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("failed to read auth response: %w", err)
 	}
 	var respData LoginResponse
-	err = json.Unmarshal(bodyBytes, &respData)
+	if err := json.Unmarshal(bodyBytes, &respData); err != nil {
+		return "", fmt.Errorf("failed to parse auth response: %w", err)
+	}
 
 	return respData.Token, nil
 }
 
-func sendPostRequest(url string, reqBody *[]byte) *http.Response {
-
+func sendPostRequest(ctx context.Context, url string, reqBody *[]byte) (*http.Response, error) {
 	bodyReader := bytes.NewReader(*reqBody)
 
-	req, err := http.NewRequest(http.MethodPost, url, bodyReader)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bodyReader)
 	if err != nil {
-		fmt.Println("Error :", err.Error())
-		return nil
+		return nil, fmt.Errorf("failed to build request for %s: %w", url, err)
 	}
 	req.Header.Set("Content-Type", "devlication/json")
 
-	client := &http.Client{}
-	res, err := client.Do(req)
+	res, err := defaultClient.Do(ctx, req)
 	if err != nil {
-		fmt.Println("Error :", err.Error())
-		return nil
+		return nil, fmt.Errorf("request to %s failed: %w", url, err)
 	}
-	return res
+	return res, nil
 }
 
-func SendGetRequest(url string, token string) *http.Response {
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+// SendGetRequest issues an authenticated GET and returns the raw response.
+// ctx governs both connection setup and the response body transfer, so
+// callers reading a large response can still be interrupted mid-read.
+func SendGetRequest(ctx context.Context, url string, token string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request for %s: %w", url, err)
+	}
 	req.Header.Set("Authorization", "Bearer "+token)
-	client := &http.Client{}
-	res, err := client.Do(req)
+	res, err := defaultClient.Do(ctx, req)
 	if err != nil {
-		fmt.Println("Error :", err.Error())
-		return nil
+		return nil, fmt.Errorf("request to %s failed: %w", url, err)
 	}
-	return res
-
+	return res, nil
 }
 
 //{"run_id":"0dfe9125-dad5-42c9-b642-5599530caa79","status":"ok"}
@@ -90,102 +105,284 @@ type CreateRunResponse struct {
 	Status string `json:"status"`
 }
 
-func SendNewRunWithKey(host string, apiKey string, appPath string, testAppPath string, commitName string, commitLink string, platform string, osVersion string, systemImage string, isolated string, filteringConfigJson string, flavor string) (string, error) {
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
-  
-	fmt.Println("Application file uploading...")
-  appFile, err := os.Open(appPath)
+// UploadOptions controls the retry/backoff behaviour of SendNewRunWithKey.
+// A nil *UploadOptions means DefaultUploadOptions().
+type UploadOptions struct {
+	MaxRetries   int
+	InitialSleep time.Duration
+	MaxSleep     time.Duration
+}
+
+func DefaultUploadOptions() UploadOptions {
+	return UploadOptions{
+		MaxRetries:   5,
+		InitialSleep: 500 * time.Millisecond,
+		MaxSleep:     30 * time.Second,
+	}
+}
+
+// sha256File hashes a file on disk without holding its contents in memory.
+func sha256File(path string) (string, error) {
+	f, err := os.Open(path)
 	if err != nil {
-		fmt.Println("Can't read apk file")
-		return "", err
+		return "", fmt.Errorf("failed to open %s for checksum: %w", path, err)
 	}
-	defer appFile.Close()
-	part, _ := writer.CreateFormFile("app", filepath.Base(appFile.Name()))
-	io.Copy(part, appFile)
-	fmt.Println("Application file uploading done")
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to checksum %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// isRetryableUploadError reports whether err (or the accompanying resp, if any)
+// represents a transient condition worth retrying: a transport-level error or
+// a 5xx response.
+func isRetryableUploadError(resp *http.Response, err error) bool {
+	if resp != nil {
+		return resp.StatusCode >= 500
+	}
+	return err != nil
+}
+
+// resumeOffsets reads the last acknowledged byte for each uploaded file from
+// the response of a failed attempt, so the next attempt can continue instead
+// of restarting from scratch. The server signals this via X-App-Upload-Offset
+// / X-TestApp-Upload-Offset headers; a missing header means "start over".
+func resumeOffsets(resp *http.Response) (appOffset int64, testAppOffset int64) {
+	if resp == nil {
+		return 0, 0
+	}
+	if v := resp.Header.Get("X-App-Upload-Offset"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			appOffset = n
+		}
+	}
+	if v := resp.Header.Get("X-TestApp-Upload-Offset"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			testAppOffset = n
+		}
+	}
+	return appOffset, testAppOffset
+}
 
-	fmt.Println("Test Application file uploading...")
-  testAppFile, err := os.Open(testAppPath)
+// progressFileReader wraps f in a progress bar proxy reader that ticks as
+// bytes flow through io.Copy, seeded at offset for resumed uploads. It
+// returns the reader to copy from and a func to call once the copy is done.
+// When ShowProgress is false it returns f unchanged and a no-op func.
+func progressFileReader(f *os.File, offset int64, label string) (io.Reader, func()) {
+	if !ShowProgress {
+		return f, func() {}
+	}
+	info, err := f.Stat()
 	if err != nil {
-		fmt.Println("Can't read testapk file")
-		return "", err
+		return f, func() {}
 	}
-	defer testAppFile.Close()
-	part2, _ := writer.CreateFormFile("testapp", filepath.Base(testAppFile.Name()))
-	io.Copy(part2, testAppFile)
-	fmt.Println("Test Application file uploading done")
+	bar := pb.New64(info.Size())
+	bar.Set(pb.Bytes, true)
+	bar.Set("prefix", label+" ")
+	bar.SetCurrent(offset)
+	bar.Start()
+	return bar.NewProxyReader(f), func() { bar.Finish() }
+}
 
-	writer.WriteField("platform", platform)
-	if len(commitName) > 0 {
-		writer.WriteField("name", commitName)
+// writeMultipartUpload streams appPath and testAppPath (from the given byte
+// offsets, for resumed attempts) plus the run's form fields into a
+// multipart.Writer, without ever holding a whole file in memory.
+func writeMultipartUpload(writer *multipart.Writer, appPath string, testAppPath string, appOffset int64, testAppOffset int64, fields map[string]string) error {
+	appFile, err := os.Open(appPath)
+	if err != nil {
+		return fmt.Errorf("can't read apk file: %w", err)
 	}
-	if len(commitLink) > 0 {
-		writer.WriteField("link", commitLink)
+	defer appFile.Close()
+	if appOffset > 0 {
+		if _, err := appFile.Seek(appOffset, io.SeekStart); err != nil {
+			return fmt.Errorf("can't resume apk file at offset %d: %w", appOffset, err)
+		}
+	}
+	part, err := writer.CreateFormFile("app", filepath.Base(appFile.Name()))
+	if err != nil {
+		return err
 	}
-	if len(osVersion) > 0 {
-		writer.WriteField("osversion", osVersion)
+	appReader, finishAppBar := progressFileReader(appFile, appOffset, "app")
+	if _, err := io.Copy(part, appReader); err != nil {
+		return fmt.Errorf("failed streaming apk file: %w", err)
 	}
-	if isolated == "true" || isolated == "false" {
-		writer.WriteField("isolated", isolated)
-  }
-	if len(systemImage) > 0 {
-		writer.WriteField("system_image", systemImage)
+	finishAppBar()
+
+	testAppFile, err := os.Open(testAppPath)
+	if err != nil {
+		return fmt.Errorf("can't read testapk file: %w", err)
+	}
+	defer testAppFile.Close()
+	if testAppOffset > 0 {
+		if _, err := testAppFile.Seek(testAppOffset, io.SeekStart); err != nil {
+			return fmt.Errorf("can't resume testapk file at offset %d: %w", testAppOffset, err)
+		}
+	}
+	part2, err := writer.CreateFormFile("testapp", filepath.Base(testAppFile.Name()))
+	if err != nil {
+		return err
 	}
-  if len(filteringConfigJson) > 0 {
-		writer.WriteField("filtering_configuration", filteringConfigJson)
+	testAppReader, finishTestAppBar := progressFileReader(testAppFile, testAppOffset, "testapp")
+	if _, err := io.Copy(part2, testAppReader); err != nil {
+		return fmt.Errorf("failed streaming testapk file: %w", err)
 	}
-  if len(flavor) > 0 {
-		writer.WriteField("flavor", flavor)
+	finishTestAppBar()
+
+	for key, value := range fields {
+		if len(value) > 0 {
+			if err := writer.WriteField(key, value); err != nil {
+				return err
+			}
+		}
 	}
+	return nil
+}
 
-	writer.Close()
+func attemptNewRunUpload(ctx context.Context, host string, apiKey string, appPath string, testAppPath string, appSHA string, testAppSHA string, appOffset int64, testAppOffset int64, fields map[string]string) (*CreateRunResponse, *http.Response, error) {
+	pipeReader, pipeWriter := io.Pipe()
+	writer := multipart.NewWriter(pipeWriter)
 
-	r, err := http.NewRequest("POST", "https://"+host+"/api/v1/run?api_key="+apiKey, body)
+	go func() {
+		err := writeMultipartUpload(writer, appPath, testAppPath, appOffset, testAppOffset, fields)
+		if err == nil {
+			err = writer.Close()
+		}
+		pipeWriter.CloseWithError(err)
+	}()
+
+	r, err := http.NewRequestWithContext(ctx, "POST", "https://"+host+"/api/v1/run?api_key="+apiKey, pipeReader)
 	if err != nil {
-		fmt.Println(err)
+		return nil, nil, err
 	}
 	r.Header.Add("Content-Type", writer.FormDataContentType())
-	client := &http.Client{}
+	r.Header.Add("X-App-SHA256", appSHA)
+	r.Header.Add("X-TestApp-SHA256", testAppSHA)
+	if appOffset > 0 || testAppOffset > 0 {
+		r.Header.Add("X-App-Upload-Offset", strconv.FormatInt(appOffset, 10))
+		r.Header.Add("X-TestApp-Upload-Offset", strconv.FormatInt(testAppOffset, 10))
+	}
 
-	fmt.Println("Making request to start the test run...")
-	resp, err := client.Do(r)
+	resp, err := defaultClient.DoOnce(ctx, r)
 	if err != nil {
-		fmt.Println(err)
-		return "", err
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return nil, resp, fmt.Errorf("%w: server returned status %d", ErrUnauthorized, resp.StatusCode)
+	}
+	if resp.StatusCode != 200 {
+		return nil, resp, fmt.Errorf("received error with status code = %d", resp.StatusCode)
 	}
-  if resp.StatusCode != 200 {
-    err = fmt.Errorf("Received error with status code = %d", resp.StatusCode)
-		return "", err
-  }
 
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		fmt.Println(err)
-		return "", err
+		return nil, resp, fmt.Errorf("failed to read run creation response: %w", err)
 	}
 	var respData CreateRunResponse
-	err = json.Unmarshal(bodyBytes, &respData)
-  if err != nil {
-		fmt.Println(err)
+	if err := json.Unmarshal(bodyBytes, &respData); err != nil {
+		return nil, resp, fmt.Errorf("failed to parse run creation response: %w", err)
 	}
+	return &respData, resp, nil
+}
 
-	fmt.Println("The test run was started. RunID=" + respData.RunID)
-	return respData.RunID, nil
+// backoffSleep returns the next sleep duration to wait before retrying,
+// applying +/-50% jitter to avoid synchronized retries across CI workers.
+// The result never exceeds max.
+func backoffSleep(sleep time.Duration, max time.Duration) time.Duration {
+	if sleep > max {
+		sleep = max
+	}
+	d := time.Duration(rand.Int63n(int64(sleep))) + sleep/2
+	if d > max {
+		d = max
+	}
+	return d
+}
+
+// SendNewRunWithKey uploads the app and test app and starts a new run.
+// Both files are streamed via io.Pipe, so memory usage stays bounded even for
+// multi-hundred-MB APKs, and a SHA-256 of each file is sent alongside the
+// upload so the server can verify integrity. Transient network errors and
+// 5xx responses are retried with exponential backoff and jitter; if the
+// server reports how many bytes it has already acknowledged, the retry
+// resumes from there rather than re-uploading from scratch. opts may be nil
+// to use DefaultUploadOptions().
+func SendNewRunWithKey(ctx context.Context, host string, apiKey string, appPath string, testAppPath string, commitName string, commitLink string, platform string, osVersion string, systemImage string, isolated string, filteringConfigJson string, flavor string, opts *UploadOptions) (string, error) {
+	if opts == nil {
+		defaults := DefaultUploadOptions()
+		opts = &defaults
+	}
+
+	appSHA, err := sha256File(appPath)
+	if err != nil {
+		return "", err
+	}
+	testAppSHA, err := sha256File(testAppPath)
+	if err != nil {
+		return "", err
+	}
+
+	fields := map[string]string{
+		"platform":                platform,
+		"name":                    commitName,
+		"link":                    commitLink,
+		"osversion":               osVersion,
+		"system_image":            systemImage,
+		"filtering_configuration": filteringConfigJson,
+		"flavor":                  flavor,
+	}
+	if isolated == "true" || isolated == "false" {
+		fields["isolated"] = isolated
+	}
+
+	fmt.Println("Application file uploading...")
+
+	var appOffset, testAppOffset int64
+	sleep := opts.InitialSleep
+	var lastErr error
+	for attempt := 0; attempt <= opts.MaxRetries; attempt++ {
+		if attempt > 0 {
+			logging.L().Warn("retrying run upload", "attempt", attempt, "maxRetries", opts.MaxRetries, "sleep", sleep, "error", lastErr)
+			select {
+			case <-time.After(sleep):
+			case <-ctx.Done():
+				return "", ctx.Err()
+			}
+			sleep = backoffSleep(sleep*2, opts.MaxSleep)
+		}
+
+		respData, resp, err := attemptNewRunUpload(ctx, host, apiKey, appPath, testAppPath, appSHA, testAppSHA, appOffset, testAppOffset, fields)
+		if err == nil {
+			fmt.Println("The test run was started. RunID=" + respData.RunID)
+			return respData.RunID, nil
+		}
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		if !isRetryableUploadError(resp, err) {
+			return "", err
+		}
+		appOffset, testAppOffset = resumeOffsets(resp)
+		lastErr = err
+	}
+
+	return "", fmt.Errorf("%w: %d attempts: %w", ErrUploadFailed, opts.MaxRetries+1, lastErr)
 }
 
 // deprecate in October 2023
-func SendNewRun(host string, token string, appPath string, testAppPath string, commitName string, commitLink string, platform string) (string, error) {
+func SendNewRun(ctx context.Context, host string, token string, appPath string, testAppPath string, commitName string, commitLink string, platform string) (string, error) {
 	appFile, err := os.Open(appPath)
 	if err != nil {
-		fmt.Println("Can't read app file")
-		return "", err
+		return "", fmt.Errorf("can't read app file: %w", err)
 	}
 	defer appFile.Close()
 	testAppFile, err := os.Open(testAppPath)
 	if err != nil {
-		fmt.Println("Can't read testapp file")
-		return "", err
+		return "", fmt.Errorf("can't read testapp file: %w", err)
 	}
 	defer testAppFile.Close()
 
@@ -208,17 +405,25 @@ func SendNewRun(host string, token string, appPath string, testAppPath string, c
 
 	writer.Close()
 
-	r, _ := http.NewRequest("POST", "https://"+host+"/api/v1/run", body)
+	r, err := http.NewRequestWithContext(ctx, "POST", "https://"+host+"/api/v1/run", body)
+	if err != nil {
+		return "", fmt.Errorf("failed to build run request: %w", err)
+	}
 	r.Header.Add("Content-Type", writer.FormDataContentType())
 	r.Header.Add("Authorization", "Bearer "+token)
-	client := &http.Client{}
-	resp, _ := client.Do(r)
+	resp, err := defaultClient.Do(ctx, r)
+	if err != nil {
+		return "", fmt.Errorf("run request failed: %w", err)
+	}
+	defer resp.Body.Close()
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("failed to read run response: %w", err)
 	}
 	var respData CreateRunResponse
-	err = json.Unmarshal(bodyBytes, &respData)
+	if err := json.Unmarshal(bodyBytes, &respData); err != nil {
+		return "", fmt.Errorf("failed to parse run response: %w", err)
+	}
 
 	return respData.RunID, nil
 }
@@ -238,103 +443,136 @@ type RunStats struct {
 	UpdatedAt    time.Time   `json:"updated"`
 }
 
+// sleepOrDone pauses for d, returning ctx.Err() early if ctx is canceled
+// first - used by the polling loops below so Ctrl-C during a long run wait
+// doesn't hang around for the rest of the poll interval.
+func sleepOrDone(ctx context.Context, d time.Duration) error {
+	select {
+	case <-time.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // Deprecate after October 2023
-func WaitRunForEnd(host string, runId string, token string) (string, error) {
+func WaitRunForEnd(ctx context.Context, host string, runId string, token string) (string, *RunStats, error) {
 	var respData RunStats
 	for {
-		client := &http.Client{}
-		req, err := http.NewRequest("GET", "https://"+host+"/api/v1/run/"+runId, nil)
+		req, err := http.NewRequestWithContext(ctx, "GET", "https://"+host+"/api/v1/run/"+runId, nil)
 		if err != nil {
-			return "", err
+			return "", nil, fmt.Errorf("failed to build run status request: %w", err)
 		}
 		req.Header.Add("Authorization", "Bearer "+token)
-		resp, err := client.Do(req)
+		resp, err := defaultClient.Do(ctx, req)
 		if err != nil {
-			return "", err
+			return "", nil, fmt.Errorf("run status request failed: %w", err)
+		}
+		if resp.StatusCode == http.StatusNotFound {
+			resp.Body.Close()
+			return "", nil, fmt.Errorf("%w: run %s", ErrRunNotFound, runId)
 		}
 		bodyBytes, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
 		if err != nil {
-			return "", err
+			return "", nil, fmt.Errorf("failed to read run status response: %w", err)
 		}
 
-		err = json.Unmarshal(bodyBytes, &respData)
+		if err := json.Unmarshal(bodyBytes, &respData); err != nil {
+			return "", nil, fmt.Errorf("failed to parse run status response: %w", err)
+		}
 		if respData.Completed.Valid == true {
 			break
 		}
-		time.Sleep(5 * time.Second)
+		if err := sleepOrDone(ctx, 5*time.Second); err != nil {
+			return "", nil, err
+		}
 	}
 	fmt.Println("Allure report - https://cloud.marathonlabs.io/api/v1/report/" + respData.ID)
 	fmt.Println("Passed - " + strconv.Itoa(int(respData.Passed.Int64)))
 	fmt.Println("Failed - " + strconv.Itoa(int(respData.Failed.Int64)))
 	fmt.Println("Ignored - " + strconv.Itoa(int(respData.Ignored.Int64)))
-	return respData.State, nil
+	return respData.State, &respData, nil
 }
 
-func WaitRunForEndWithApiKey(host string, runId string, apiKey string) (string, error) {
+func WaitRunForEndWithApiKey(ctx context.Context, host string, runId string, apiKey string) (string, *RunStats, error) {
 	fmt.Println("Waiting for the test run finish...")
 	var respData RunStats
 	for {
-		client := &http.Client{}
-		req, err := http.NewRequest("GET", "https://"+host+"/api/v1/run/"+runId+"?api_key="+apiKey, nil)
+		req, err := http.NewRequestWithContext(ctx, "GET", "https://"+host+"/api/v1/run/"+runId+"?api_key="+apiKey, nil)
 		if err != nil {
-			return "", err
+			return "", nil, fmt.Errorf("failed to build run status request: %w", err)
 		}
-		resp, err := client.Do(req)
+		resp, err := defaultClient.Do(ctx, req)
 		if err != nil {
-			return "", err
+			return "", nil, fmt.Errorf("run status request failed: %w", err)
+		}
+		if resp.StatusCode == http.StatusNotFound {
+			resp.Body.Close()
+			return "", nil, fmt.Errorf("%w: run %s", ErrRunNotFound, runId)
+		}
+		if resp.StatusCode != http.StatusOK {
+			logging.L().Warn("unexpected run status response, retrying", "statusCode", resp.StatusCode)
+			resp.Body.Close()
+			if err := sleepOrDone(ctx, 5*time.Second); err != nil {
+				return "", nil, err
+			}
+			continue
 		}
-    if resp.StatusCode != 200 {
-      fmt.Println(fmt.Sprintf("Status code = %d. Maybe it is a critical error", resp.StatusCode))
-      continue
-    }
 
 		bodyBytes, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
 		if err != nil {
-			return "", err
+			return "", nil, fmt.Errorf("failed to read run status response: %w", err)
 		}
 
-		err = json.Unmarshal(bodyBytes, &respData)
+		if err := json.Unmarshal(bodyBytes, &respData); err != nil {
+			return "", nil, fmt.Errorf("failed to parse run status response: %w", err)
+		}
 		if respData.Completed.Valid == true {
 			break
 		}
-		time.Sleep(5 * time.Second)
+		if err := sleepOrDone(ctx, 5*time.Second); err != nil {
+			return "", nil, err
+		}
 	}
 	fmt.Println("Allure report - https://cloud.marathonlabs.io/api/v1/report/" + respData.ID)
 	fmt.Println("Passed - " + strconv.Itoa(int(respData.Passed.Int64)))
 	fmt.Println("Failed - " + strconv.Itoa(int(respData.Failed.Int64)))
 	fmt.Println("Ignored - " + strconv.Itoa(int(respData.Ignored.Int64)))
-	return respData.State, nil
+	return respData.State, &respData, nil
 }
 
 type TokenResponse struct {
 	Token string `json:"token"`
 }
 
-func RequestJwtToken(host string, apiKey string) (string, error) {
+func RequestJwtToken(ctx context.Context, host string, apiKey string) (string, error) {
 	fmt.Println("Token is requesting...")
 	var tokenObj TokenResponse
-	client := &http.Client{}
-	req, err := http.NewRequest("GET", "https://"+host+"/api/v1/user/jwt?api_key="+apiKey, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", "https://"+host+"/api/v1/user/jwt?api_key="+apiKey, nil)
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("failed to build jwt request: %w", err)
 	}
-	resp, err := client.Do(req)
+	resp, err := defaultClient.Do(ctx, req)
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("jwt request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return "", fmt.Errorf("%w: server returned status %d", ErrUnauthorized, resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("received error with status code = %d", resp.StatusCode)
 	}
-  if resp.StatusCode != 200 {
-    err = fmt.Errorf("Received error with status code = %d", resp.StatusCode)
-		return "", err
-  }
 
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("failed to read jwt response: %w", err)
 	}
 
-	err = json.Unmarshal(bodyBytes, &tokenObj)
-	if err != nil {
-		return "", err
+	if err := json.Unmarshal(bodyBytes, &tokenObj); err != nil {
+		return "", fmt.Errorf("failed to parse jwt response: %w", err)
 	}
 	fmt.Println("Token was received")
 	return tokenObj.Token, nil
@@ -0,0 +1,42 @@
+package request
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// TestResult is one test's outcome within a run, as reported by the
+// per-test results endpoint. It is independent of the Allure artifact tree,
+// so report emitters work even when the user hasn't requested -o.
+type TestResult struct {
+	Name           string `json:"name"`
+	Suite          string `json:"suite"`
+	Device         string `json:"device,omitempty"` // device/emulator the test ran on, used to shard JUnit suites
+	Status         string `json:"status"`           // "passed", "failed", or "ignored"
+	DurationMs     int64  `json:"duration_ms"`
+	FailureMessage string `json:"failure_message,omitempty"`
+	StackTrace     string `json:"stack_trace,omitempty"`
+}
+
+// GetTestResults fetches the per-test outcomes for runId, parallel to how
+// GetArtifacts in package allure traverses the artifact tree.
+func GetTestResults(ctx context.Context, host string, token string, runId string) ([]TestResult, error) {
+	resp, err := SendGetRequest(ctx, "https://"+host+"/api/v1/run/"+runId+"/tests", token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch test results for run %s: %w", runId, err)
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read test results for run %s: %w", runId, err)
+	}
+
+	var results []TestResult
+	if err := json.Unmarshal(bodyBytes, &results); err != nil {
+		return nil, fmt.Errorf("failed to parse test results for run %s: %w", runId, err)
+	}
+	return results, nil
+}
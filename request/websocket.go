@@ -0,0 +1,133 @@
+package request
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"time"
+
+	"cli/logging"
+
+	"github.com/gorilla/websocket"
+)
+
+// RuntimeState is one status update pushed over a run's progress feed.
+type RuntimeState struct {
+	TotalEmulators   int    `json:"total_emulators"`
+	WorkingEmulators int    `json:"working_emulators"`
+	State            string `json:"state"`
+	Percents         int    `json:"percents"`
+	TestName         string `json:"test_name"`
+	TestState        string `json:"test_state"`
+}
+
+const (
+	subscribeInitialBackoff = 100 * time.Millisecond
+	subscribeMaxBackoff     = 30 * time.Second
+	subscribeCloseTimeout   = time.Second
+)
+
+// Subscribe streams live progress for runId over the runtime websocket feed.
+// It returns immediately; a background goroutine dials the feed, sends each
+// RuntimeState it receives on the returned channel, and reconnects with
+// jittered exponential backoff (100ms-30s) on a dropped connection or read
+// error, reporting the error on the second channel. The same State value is
+// not resent on consecutive updates, so a reconnect doesn't cause the
+// caller to see a duplicated status line.
+//
+// Both channels are closed, and the connection (if any) is closed cleanly
+// with a close frame, once ctx is canceled. Callers that want the feed to
+// stop once a run reaches a terminal state - rather than running for the
+// lifetime of a longer-lived parent context - should derive ctx with
+// context.WithCancel and cancel it once WaitRunForEnd/WaitRunForEndWithApiKey
+// returns.
+func Subscribe(ctx context.Context, token string, runId string) (<-chan RuntimeState, <-chan error) {
+	states := make(chan RuntimeState)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(states)
+		defer close(errs)
+
+		u := url.URL{Scheme: "ws", Host: "devruntime.testwise.pro:1005", Path: "/hello", RawQuery: "token=" + token + "&run_id=" + runId}
+		backoff := subscribeInitialBackoff
+		var lastState string
+
+		for {
+			conn, _, err := websocket.DefaultDialer.DialContext(ctx, u.String(), nil)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				select {
+				case errs <- err:
+				default:
+				}
+				if sleepOrDone(ctx, backoff) != nil {
+					return
+				}
+				backoff = backoffSleep(backoff*2, subscribeMaxBackoff)
+				continue
+			}
+			backoff = subscribeInitialBackoff
+
+			if readRuntimeFeed(ctx, conn, states, errs, &lastState) {
+				return
+			}
+			if sleepOrDone(ctx, backoff) != nil {
+				return
+			}
+			backoff = backoffSleep(backoff*2, subscribeMaxBackoff)
+		}
+	}()
+
+	return states, errs
+}
+
+// readRuntimeFeed reads messages from conn until ctx is canceled or the
+// connection drops, forwarding each to states. It reports whether the feed
+// should stop entirely (true) rather than reconnect (false).
+func readRuntimeFeed(ctx context.Context, conn *websocket.Conn, states chan<- RuntimeState, errs chan<- error, lastState *string) bool {
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(subscribeCloseTimeout))
+			conn.Close()
+		case <-done:
+		}
+	}()
+	defer close(done)
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			if ctx.Err() != nil {
+				return true
+			}
+			select {
+			case errs <- err:
+			default:
+			}
+			return false
+		}
+
+		var message RuntimeState
+		if err := json.Unmarshal(data, &message); err != nil {
+			logging.L().Warn("failed to parse runtime progress message", "error", err)
+			continue
+		}
+		if len(message.State) > 0 && message.State == *lastState {
+			continue
+		}
+		if len(message.State) > 0 {
+			*lastState = message.State
+		}
+
+		select {
+		case states <- message:
+		case <-ctx.Done():
+			return true
+		}
+	}
+}